@@ -22,10 +22,11 @@ mac-arm64:darwin:arm64
 mac-x64:darwin:amd64
 linux:linux:amd64"
 
-# Find all tool directories (directories containing go.mod)
+# Find all tool directories (directories containing go.mod and a main
+# package; modules shared by the tools have none)
 find_tools() {
     for dir in "$PROJECT_ROOT"/*/; do
-        if [[ -f "${dir}go.mod" ]]; then
+        if [[ -f "${dir}go.mod" ]] && grep -qs '^package main' "${dir}"*.go; then
             basename "$dir"
         fi
     done
@@ -72,6 +73,23 @@ create_platform_dirs() {
     print_success "Platform directories created"
 }
 
+# Run go generate for tools with generate directives, which download files
+# embedded into the binaries. A failed download fails the build rather than
+# producing binaries without the files.
+generate_sources() {
+    for tool in $(find_tools); do
+        local tool_dir="$PROJECT_ROOT/$tool"
+        if grep -rqs --include='*.go' '^//go:generate' "$tool_dir"; then
+            print_step "Generating sources for $tool..."
+            if ! (cd "$tool_dir" && go generate ./...); then
+                print_error "go generate failed for $tool"
+                exit 1
+            fi
+            print_success "Sources generated for $tool"
+        fi
+    done
+}
+
 # Build tool for a specific platform
 build_tool() {
     local tool_name="$1"
@@ -164,6 +182,8 @@ main() {
     echo -e "Go version: $(go version)"
     echo ""
     
+    generate_sources
+    echo ""
     clean_releases
     create_platform_dirs
     echo ""
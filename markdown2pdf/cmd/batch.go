@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"github.com/example/markdown2pdf/converter"
+	"github.com/example/shared/batch"
+)
+
+// runBatch converts jobs in tabs of one shared browser, then prints a
+// summary. It returns an error if any file failed, after trying all of them.
+func runBatch(jobs []batch.Job, opts converter.Options, deps []string) error {
+	return batch.Run(jobs, jobCount, force, deps, func(workers int) (batch.ConvertFunc, func(), error) {
+		pool, err := converter.NewBrowserPoolWithOptions(workers, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		convert := func(j batch.Job) ([]string, error) {
+			c := converter.NewWithBrowser(opts, pool)
+			err := c.ConvertFile(j.Input, j.Output)
+			return c.Warnings(), err
+		}
+		return convert, func() { pool.Close() }, nil
+	})
+}
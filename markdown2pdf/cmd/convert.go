@@ -2,11 +2,15 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
+	"runtime"
+	"slices"
+	"time"
 
 	"github.com/example/markdown2pdf/converter"
+	"github.com/example/shared/batch"
 	"github.com/spf13/cobra"
 )
 
@@ -32,15 +36,53 @@ var (
 	// Custom CSS file
 	cssFile string
 
+	// Syntax highlighting style
+	codeStyle string
+
+	// Fail when images or other local assets are missing
+	failOnMissingAssets bool
+
+	// Page header and footer
+	headerTemplate string
+	footerTemplate string
+	pageNumbers    bool
+
+	// Table of contents
+	toc      bool
+	tocDepth int
+
+	// Render the front matter title block
+	titleBlock bool
+
+	// Browser to launch or connect to, and the rendering time limit
+	chromePath         string
+	chromeFlags        []string
+	remoteDebuggingURL string
+	timeout            time.Duration
+
+	// Batch conversion
+	outDir    string
+	recursive bool
+	jobCount  int
+	force     bool
+
 	// Convert command
 	convertCmd = &cobra.Command{
-		Use:   "convert <input.md>",
+		Use:   "convert <input.md|dir|glob>...",
 		Short: "Convert a Markdown file to PDF",
 		Long: `Convert a Markdown file to PDF format.
 
-The convert command takes a Markdown file as input and generates a PDF file.
-By default, the output file will have the same name as the input file but with
-a .pdf extension.
+The convert command takes one or more Markdown files as input and generates a
+PDF file for each. By default, the output file will have the same name as the
+input file but with a .pdf extension.
+
+Inputs may also be directories (searched recursively with --recursive) or glob
+patterns, where "**" matches any number of directories. When several files are
+converted, they are rendered in a single shared browser by --jobs parallel
+workers, files whose PDF is newer than the Markdown source and the --css and
+template files are skipped unless --force is given, and a summary is printed
+at the end. Changes to other options aren't noticed, so give --force after
+changing them. The command exits with an error if any file failed.
 
 Supported paper sizes:
   - A4 (default): 210mm x 297mm
@@ -57,6 +99,18 @@ Examples:
   # Specify output file
   markdown2pdf convert README.md -o documentation.pdf
 
+  # Read Markdown from standard input and write the PDF to standard output
+  cat README.md | markdown2pdf convert - > README.pdf
+
+  # Convert several files
+  markdown2pdf convert intro.md guide.md faq.md
+
+  # Convert a directory tree into build/pdf with 4 parallel workers
+  markdown2pdf convert --recursive docs/ --out-dir build/pdf --jobs 4
+
+  # Convert all Markdown files below docs matching a pattern
+  markdown2pdf convert "docs/**/*.md"
+
   # Use Letter paper size with landscape orientation
   markdown2pdf convert README.md --paper-size Letter --landscape
 
@@ -64,8 +118,21 @@ Examples:
   markdown2pdf convert README.md --margin-top 25 --margin-bottom 25 --margin-left 20 --margin-right 20
 
   # Include background graphics and custom CSS
-  markdown2pdf convert README.md --print-background --css custom-style.css`,
-		Args: cobra.ExactArgs(1),
+  markdown2pdf convert README.md --print-background --css custom-style.css
+
+  # Add page numbers, or a custom footer
+  markdown2pdf convert README.md --page-numbers
+  markdown2pdf convert README.md --footer-template "{{title}} - page {{page}} of {{pages}}"
+
+  # Add a table of contents of the first two heading levels
+  markdown2pdf convert README.md --toc --toc-depth 2
+
+  # Use Chromium from a custom location, e.g. as root in a container
+  markdown2pdf convert README.md --chrome-path /opt/chromium/chrome --chrome-flag no-sandbox
+
+  # Render with an already running browser and allow 5 minutes per document
+  markdown2pdf convert README.md --remote-debugging-url ws://127.0.0.1:9222 --timeout 5m`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: runConvert,
 	}
 )
@@ -93,60 +160,203 @@ func init() {
 
 	// CSS file flag
 	convertCmd.Flags().StringVar(&cssFile, "css", "", "Custom CSS file to apply to the PDF")
+
+	// Code style flag
+	convertCmd.Flags().StringVar(&codeStyle, "code-style", converter.DefaultCodeStyle, "Syntax highlighting style for code blocks (e.g. github, monokai, dracula)")
+
+	// Missing assets flag
+	convertCmd.Flags().BoolVar(&failOnMissingAssets, "fail-on-missing-assets", false, "Fail if images or other local assets referenced by the document are missing")
+
+	// Header and footer flags
+	convertCmd.Flags().StringVar(&headerTemplate, "header-template", "", "Page header: HTML file or inline HTML with {{title}}, {{date}}, {{page}}, {{pages}} placeholders")
+	convertCmd.Flags().StringVar(&footerTemplate, "footer-template", "", "Page footer: HTML file or inline HTML with {{title}}, {{date}}, {{page}}, {{pages}} placeholders")
+	convertCmd.Flags().BoolVar(&toc, "toc", false, "Insert a table of contents at the [TOC] marker, or at the start of the document")
+	convertCmd.Flags().IntVar(&tocDepth, "toc-depth", converter.DefaultTOCDepth, "Deepest heading level listed in the table of contents")
+	convertCmd.Flags().BoolVar(&titleBlock, "title-block", false, "Render the front matter title, author and date at the top of the document")
+	convertCmd.Flags().BoolVar(&pageNumbers, "page-numbers", false, "Add page numbers (\"page / pages\") to the footer")
+
+	// Browser flags
+	convertCmd.Flags().StringVar(&chromePath, "chrome-path", "", "Chrome or Chromium executable (default: search the PATH)")
+	convertCmd.Flags().StringArrayVar(&chromeFlags, "chrome-flag", nil, "Extra browser flag as name or name=value, e.g. no-sandbox (repeatable)")
+	convertCmd.Flags().StringVar(&remoteDebuggingURL, "remote-debugging-url", "", "DevTools URL of a running browser on this machine to use instead of launching one")
+	convertCmd.Flags().DurationVar(&timeout, "timeout", converter.DefaultTimeout, "Maximum time to render each document (e.g. 90s, 5m)")
+
+	// Batch flags
+	convertCmd.Flags().StringVar(&outDir, "out-dir", "", "Write PDFs to this directory, mirroring the input directory tree")
+	convertCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Convert Markdown files in subdirectories of directory inputs")
+	convertCmd.Flags().IntVarP(&jobCount, "jobs", "j", runtime.NumCPU(), "Number of files to convert in parallel")
+	convertCmd.Flags().BoolVar(&force, "force", false, "Convert files even if their PDF is up to date")
 }
 
 func runConvert(cmd *cobra.Command, args []string) error {
-	inputFile := args[0]
+	// "-" reads Markdown from standard input and, without --output, writes
+	// the PDF to standard output
+	if slices.Contains(args, stdio) {
+		if len(args) > 1 {
+			return fmt.Errorf("standard input (-) cannot be combined with other inputs")
+		}
+		opts, err := converterOptions()
+		if err != nil {
+			return err
+		}
+		output := outputFile
+		if output == "" {
+			output = stdio
+		}
+		return convertFile(converter.New(opts), stdio, output)
+	}
 
-	// Validate input file exists
-	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
-		return fmt.Errorf("input file does not exist: %s", inputFile)
+	jobs, err := batch.Collect(args, ".pdf", outDir, recursive)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		return fmt.Errorf("no Markdown files found")
 	}
 
-	// Check if input file is a Markdown file
-	ext := strings.ToLower(filepath.Ext(inputFile))
-	if ext != ".md" && ext != ".markdown" {
-		fmt.Fprintf(os.Stderr, "Warning: input file does not have .md or .markdown extension\n")
+	opts, err := converterOptions()
+	if err != nil {
+		return err
 	}
 
-	// Determine output file path
-	output := outputFile
-	if output == "" {
-		// Replace extension with .pdf
-		baseName := strings.TrimSuffix(inputFile, filepath.Ext(inputFile))
-		output = baseName + ".pdf"
+	// A single file given by name is always converted, without a summary
+	if len(args) == 1 && len(jobs) == 1 && !batch.IsGlob(args[0]) && jobs[0].Input == args[0] {
+		inputFile := jobs[0].Input
+
+		// Check if input file is a Markdown file
+		if !batch.IsMarkdown(inputFile) {
+			fmt.Fprintf(os.Stderr, "Warning: input file does not have .md or .markdown extension\n")
+		}
+
+		output := jobs[0].Output
+		if outputFile != "" {
+			output = outputFile
+		} else if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		return convertFile(converter.New(opts), inputFile, output)
+	}
+
+	if outputFile != "" {
+		return fmt.Errorf("--output can only be used with a single input file; use --out-dir instead")
 	}
 
+	// Conversions are redone when the stylesheet or templates change
+	var deps []string
+	for _, path := range []string{cssFile, headerTemplate, footerTemplate} {
+		if info, err := os.Stat(path); path != "" && err == nil && !info.IsDir() {
+			deps = append(deps, path)
+		}
+	}
+
+	return runBatch(jobs, opts, deps)
+}
+
+// converterOptions builds the converter options from the command line flags
+func converterOptions() (converter.Options, error) {
 	// Read custom CSS if provided
 	var customCSS string
 	if cssFile != "" {
 		cssContent, err := os.ReadFile(cssFile)
 		if err != nil {
-			return fmt.Errorf("failed to read CSS file: %w", err)
+			return converter.Options{}, fmt.Errorf("failed to read CSS file: %w", err)
 		}
 		customCSS = string(cssContent)
 	}
 
-	// Create converter options
-	opts := converter.Options{
-		PaperSize:       paperSize,
-		MarginTop:       marginTop,
-		MarginBottom:    marginBottom,
-		MarginLeft:      marginLeft,
-		MarginRight:     marginRight,
-		PrintBackground: printBackground,
-		Landscape:       landscape,
-		CustomCSS:       customCSS,
+	// Read header and footer templates
+	header, err := readTemplate(headerTemplate)
+	if err != nil {
+		return converter.Options{}, fmt.Errorf("failed to read header template: %w", err)
+	}
+	footer, err := readTemplate(footerTemplate)
+	if err != nil {
+		return converter.Options{}, fmt.Errorf("failed to read footer template: %w", err)
 	}
 
-	// Convert the file
-	fmt.Printf("Converting %s to %s...\n", inputFile, output)
+	return converter.Options{
+		PaperSize:           paperSize,
+		MarginTop:           marginTop,
+		MarginBottom:        marginBottom,
+		MarginLeft:          marginLeft,
+		MarginRight:         marginRight,
+		PrintBackground:     printBackground,
+		Landscape:           landscape,
+		CustomCSS:           customCSS,
+		CodeStyle:           codeStyle,
+		FailOnMissingAssets: failOnMissingAssets,
+		HeaderTemplate:      header,
+		FooterTemplate:      footer,
+		PageNumbers:         pageNumbers,
+		TOC:                 toc,
+		TOCDepth:            tocDepth,
+		TitleBlock:          titleBlock,
+		ChromePath:          chromePath,
+		ChromeFlags:         chromeFlags,
+		RemoteDebuggingURL:  remoteDebuggingURL,
+		Timeout:             timeout,
+	}, nil
+}
+
+// stdio is the file name standing for standard input or output
+const stdio = "-"
 
-	c := converter.New(opts)
-	if err := c.ConvertFile(inputFile, output); err != nil {
+// convertFile converts one file and prints its progress and warnings. An
+// input or output of "-" means standard input or output, in which case
+// progress goes to standard error.
+func convertFile(c *converter.Converter, inputFile, output string) error {
+	status := os.Stdout
+	if output == stdio {
+		status = os.Stderr
+	}
+	fmt.Fprintf(status, "Converting %s to %s...\n", displayName(inputFile, "stdin"), displayName(output, "stdout"))
+
+	var err error
+	switch {
+	case inputFile == stdio && output == stdio:
+		err = c.ConvertReader(os.Stdin, os.Stdout)
+	case inputFile == stdio:
+		var content []byte
+		if content, err = io.ReadAll(os.Stdin); err == nil {
+			err = c.Convert(content, output)
+		}
+	case output == stdio:
+		err = c.ConvertFileTo(inputFile, os.Stdout)
+	default:
+		err = c.ConvertFile(inputFile, output)
+	}
+	if err != nil {
 		return fmt.Errorf("conversion failed: %w", err)
 	}
 
-	fmt.Printf("Successfully converted to %s\n", output)
+	for _, warning := range c.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	fmt.Fprintf(status, "Successfully converted to %s\n", displayName(output, "stdout"))
 	return nil
 }
+
+// displayName names a file in progress messages, using std for "-"
+func displayName(path, std string) string {
+	if path == stdio {
+		return std
+	}
+	return path
+}
+
+// readTemplate returns the contents of value if it names an existing file,
+// and value itself otherwise
+func readTemplate(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if info, err := os.Stat(value); err == nil && !info.IsDir() {
+		content, err := os.ReadFile(value)
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	}
+	return value, nil
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/markdown2pdf/converter"
+	"github.com/spf13/pflag"
+)
+
+// execute runs the command line args with stdin as standard input and
+// returns what was written to standard output and standard error
+func execute(t *testing.T, stdin string, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	var files [3]*os.File
+	for i, name := range []string{"stdin", "stdout", "stderr"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files[i] = f
+	}
+	if _, err := io.WriteString(files[0], stdin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files[0].Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := [3]*os.File{os.Stdin, os.Stdout, os.Stderr}
+	os.Stdin, os.Stdout, os.Stderr = files[0], files[1], files[2]
+	defer func() { os.Stdin, os.Stdout, os.Stderr = saved[0], saved[1], saved[2] }()
+
+	// Flags keep their values between executions
+	convertCmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if list, ok := f.Value.(interface{ Replace([]string) error }); ok {
+			list.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+
+	out, readErr := os.ReadFile(files[1].Name())
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	errOut, readErr := os.ReadFile(files[2].Name())
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	return string(out), string(errOut), err
+}
+
+// The conversions below stop before printing, so they need no browser: a
+// front matter stylesheet that doesn't exist shows that the Markdown was
+// read, and a browser that doesn't exist shows that it was rendered.
+
+func TestConvertStdinToStdout(t *testing.T) {
+	stdout, stderr, err := execute(t, "---\ncss: missing.css\n---\nText\n", "convert", "-")
+	if err == nil || !strings.Contains(err.Error(), "failed to read front matter CSS") {
+		t.Errorf("got error %v, want the stylesheet not found", err)
+	}
+	if !strings.Contains(stderr, "Converting stdin to stdout...") {
+		t.Errorf("progress not on standard error: %q", stderr)
+	}
+	if stdout != "" {
+		t.Errorf("wrote %q to standard output", stdout)
+	}
+}
+
+func TestConvertFileToStdout(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "doc.md")
+	if err := os.WriteFile(input, []byte("Text\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := execute(t, "", "convert", input, "-o", "-", "--chrome-path", filepath.Join(dir, "no-browser"))
+	if err == nil || !strings.Contains(err.Error(), "browser not found") {
+		t.Errorf("got error %v, want the browser not found", err)
+	}
+	if !strings.Contains(stderr, "Converting "+input+" to stdout...") {
+		t.Errorf("progress not on standard error: %q", stderr)
+	}
+	if stdout != "" {
+		t.Errorf("wrote %q to standard output", stdout)
+	}
+}
+
+func TestConvertStdinToFile(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "out.pdf")
+
+	stdout, _, err := execute(t, "Text\n", "convert", "-", "-o", output, "--chrome-path", filepath.Join(dir, "no-browser"))
+	if err == nil || !strings.Contains(err.Error(), "browser not found") {
+		t.Errorf("got error %v, want the browser not found", err)
+	}
+	if !strings.Contains(stdout, "Converting stdin to "+output+"...") {
+		t.Errorf("progress not on standard output: %q", stdout)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("failed conversion left %s: %v", output, err)
+	}
+}
+
+func TestConvertStdinWithOtherInputs(t *testing.T) {
+	stdout, _, err := execute(t, "Text\n", "convert", "-", "other.md")
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Errorf("got error %v", err)
+	}
+	if stdout != "" {
+		t.Errorf("wrote %q", stdout)
+	}
+}
+
+func TestConvertBrowserOptions(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "no-browser")
+
+	_, _, err := execute(t, "Text\n", "convert", "-", "-o", filepath.Join(dir, "out.pdf"),
+		"--chrome-path", missing, "--chrome-flag", "--no-sandbox", "--chrome-flag", "window-size=1280,800", "--timeout", "90s")
+	if err == nil || !strings.Contains(err.Error(), "browser not found at "+missing) {
+		t.Errorf("got error %v, want the browser not found", err)
+	}
+	opts, err := converterOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"--no-sandbox", "window-size=1280,800"}; !slices.Equal(opts.ChromeFlags, want) {
+		t.Errorf("got flags %q, want %q", opts.ChromeFlags, want)
+	}
+	if opts.ChromePath != missing || opts.Timeout != 90*time.Second {
+		t.Errorf("got path %q and timeout %s", opts.ChromePath, opts.Timeout)
+	}
+
+	// The next execution starts from the defaults again
+	execute(t, "Text\n", "convert", "-", "-o", filepath.Join(dir, "out.pdf"), "--chrome-path", missing)
+	if opts, err = converterOptions(); err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.ChromeFlags) != 0 || opts.Timeout != converter.DefaultTimeout {
+		t.Errorf("got flags %q and timeout %s, want the defaults", opts.ChromeFlags, opts.Timeout)
+	}
+
+	if _, _, err := execute(t, "Text\n", "convert", "-", "--timeout", "90"); err == nil || !strings.Contains(err.Error(), "--timeout") {
+		t.Errorf("timeout without a unit: got error %v", err)
+	}
+}
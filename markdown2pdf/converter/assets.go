@@ -0,0 +1,208 @@
+package converter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// documentServer serves the rendered HTML over a loopback HTTP server
+// together with the files of the Markdown source's directory. Everything is
+// served under a random path, so other local processes can't guess where
+// the files are, and nothing outside the directory is served: the page may
+// contain scripts from raw HTML, and they must not be able to read arbitrary
+// files. References leaving the directory are refused and recorded.
+type documentServer struct {
+	URL      string
+	baseDir  string
+	pagePath string // Random path prefix of the page and the directory
+	html     string
+	server   *http.Server
+
+	mu      sync.Mutex
+	missing map[string]bool
+	outside map[string]bool
+}
+
+// serveDocument starts a loopback server for htmlContent, whose relative
+// references resolve against baseDir
+func serveDocument(htmlContent, baseDir string) (*documentServer, error) {
+	if baseDir == "" {
+		baseDir = "."
+	}
+	absDir, err := filepath.Abs(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve base directory: %w", err)
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to start document server: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start document server: %w", err)
+	}
+
+	s := &documentServer{
+		baseDir:  absDir,
+		pagePath: "/" + hex.EncodeToString(token) + "/",
+		html:     htmlContent,
+		missing:  map[string]bool{},
+		outside:  map[string]bool{},
+	}
+	s.URL = "http://" + listener.Addr().String() + s.pagePath
+	s.server = &http.Server{Handler: s}
+	go s.server.Serve(listener)
+
+	return s, nil
+}
+
+// ServeHTTP serves the document at the page path, the bundled files under
+// bundledPath and the files of the base directory below the page path,
+// recording requests for files that don't exist or lie outside the
+// directory
+func (s *documentServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Resolve dot segments a client left in, so they can't leave the
+	// directory unnoticed
+	urlPath := path.Clean(r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") && urlPath != "/" {
+		urlPath += "/"
+	}
+
+	switch {
+	case urlPath == s.pagePath:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(s.html))
+
+	case strings.HasPrefix(urlPath, bundledPath):
+		http.StripPrefix(bundledPath, http.FileServer(http.FS(bundledFS()))).ServeHTTP(w, r)
+
+	case strings.HasPrefix(urlPath, s.pagePath):
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		http.StripPrefix(strings.TrimSuffix(s.pagePath, "/"), http.FileServer(http.Dir(s.baseDir))).ServeHTTP(rec, r)
+		if rec.status == http.StatusNotFound {
+			s.record(s.missing, strings.TrimPrefix(urlPath, s.pagePath))
+		}
+
+	default:
+		http.Error(w, "outside the document directory", http.StatusForbidden)
+		if urlPath != "/favicon.ico" {
+			s.record(s.outside, urlPath)
+		}
+	}
+}
+
+// record adds a URL path to one of the request sets
+func (s *documentServer) record(set map[string]bool, urlPath string) {
+	s.mu.Lock()
+	set[urlPath] = true
+	s.mu.Unlock()
+}
+
+// resolve returns the server URL path a reference in the page requests, or
+// false for references that don't go to the server
+func (s *documentServer) resolve(ref string) (string, bool) {
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+	resolved := path.Join(s.pagePath, u.Path)
+	if strings.HasPrefix(u.Path, "/") {
+		resolved = path.Clean(u.Path)
+	}
+	if resolved+"/" == s.pagePath {
+		resolved += "/"
+	}
+	return resolved, true
+}
+
+// Missing returns the paths, relative to the base directory, of requested
+// files that don't exist
+func (s *documentServer) Missing() []string {
+	return s.requests(s.missing)
+}
+
+// Outside returns the URL paths of requests that were refused because they
+// lie outside the base directory
+func (s *documentServer) Outside() []string {
+	return s.requests(s.outside)
+}
+
+// requests returns a request set, sorted
+func (s *documentServer) requests(set map[string]bool) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var paths []string
+	for p := range set {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// BaseFileURL returns the file:// URL of the base directory, used to point
+// links at local files rather than at the server
+func (s *documentServer) BaseFileURL() string {
+	dir := filepath.ToSlash(s.baseDir)
+	if !strings.HasPrefix(dir, "/") {
+		dir = "/" + dir // A Windows path such as C:/docs
+	}
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	return (&url.URL{Scheme: "file", Path: dir}).String()
+}
+
+// Close stops the server
+func (s *documentServer) Close() error {
+	return s.server.Close()
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// waitForAssetsScript waits until every image has loaded or failed and web
+// fonts are ready, then returns the sources of images that failed to load
+const waitForAssetsScript = `Promise.all(Array.from(document.images).map(img =>
+	img.complete ? null : new Promise(resolve => {
+		img.addEventListener('load', resolve);
+		img.addEventListener('error', resolve);
+	})
+)).then(() => document.fonts.ready).then(() =>
+	Array.from(document.images)
+		.filter(img => img.naturalWidth === 0)
+		.map(img => img.getAttribute('src') || '')
+)`
+
+// rewriteLinksScript points local links, which the browser resolved against
+// the document server, at the files themselves by resolving them again
+// against the file:// URL of the base directory
+const rewriteLinksScript = `((base) => {
+	for (const a of document.querySelectorAll('a[href]')) {
+		const href = a.getAttribute('href');
+		if (href.startsWith('#')) continue;
+		if (new URL(a.href).origin === location.origin) {
+			a.href = new URL(href, base).href;
+		}
+	}
+	return true;
+})(%q)`
@@ -0,0 +1,198 @@
+package converter
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fetch requests ref resolved against the page URL, as the browser would
+func fetch(t *testing.T, page, ref string) (int, string) {
+	t.Helper()
+
+	base, err := url.Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := base.Parse(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(target.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestDocumentServerPaths(t *testing.T) {
+	root := t.TempDir()
+	docs := filepath.Join(root, "docs")
+	files := map[string]string{
+		filepath.Join(docs, "local.png"):         "local",
+		filepath.Join(docs, "sub dir", "a.png"):  "nested",
+		filepath.Join(root, "img", "parent.png"): "parent",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv, err := serveDocument("<p>page</p>", docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"", "<p>page</p>"},
+		{"local.png", "local"},
+		{"./local.png", "local"},
+		{"sub%20dir/a.png", "nested"},
+	}
+	for _, tt := range tests {
+		status, body := fetch(t, srv.URL, tt.ref)
+		if status != http.StatusOK || body != tt.want {
+			t.Errorf("%q: got %d %q, want 200 %q", tt.ref, status, body, tt.want)
+		}
+	}
+
+	absRef := (&url.URL{Path: filepath.ToSlash(filepath.Join(root, "img", "parent.png"))}).EscapedPath()
+	docsRef := (&url.URL{Path: filepath.ToSlash(filepath.Join(docs, "local.png"))}).EscapedPath()
+	for _, ref := range []string{"../img/parent.png", absRef, docsRef, "/etc/passwd"} {
+		if status, body := fetch(t, srv.URL, ref); status != http.StatusForbidden {
+			t.Errorf("%q: got %d %q, want 403", ref, status, body)
+		}
+	}
+
+	// A path that isn't cleaned by the client must not escape either
+	resp, err := http.Get(srv.URL + "../img/parent.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK || string(body) == "parent" {
+		t.Errorf("unclean path: got %d %q", resp.StatusCode, body)
+	}
+
+	if status, _ := fetch(t, srv.URL, "missing.png"); status != http.StatusNotFound {
+		t.Errorf("missing file: got status %d, want 404", status)
+	}
+	if got, want := srv.Missing(), []string{"missing.png"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Missing() = %q, want %q", got, want)
+	}
+	want := []string{
+		filepath.ToSlash(filepath.Join(root, "img", "parent.png")),
+		filepath.ToSlash(filepath.Join(docs, "local.png")),
+		"/etc/passwd",
+		"/img/parent.png",
+	}
+	sort.Strings(want)
+	if got := srv.Outside(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Outside() = %q, want %q", got, want)
+	}
+}
+
+func TestDocumentServerURLs(t *testing.T) {
+	srv, err := serveDocument("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	other, err := serveDocument("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if !strings.HasPrefix(srv.URL, "http://127.0.0.1:") || len(srv.pagePath) < 32 || srv.pagePath == other.pagePath {
+		t.Errorf("page paths %q and %q are not random", srv.URL, other.pagePath)
+	}
+
+	tests := []struct {
+		ref   string
+		want  string
+		local bool
+	}{
+		{"a.png", srv.pagePath + "a.png", true},
+		{"./sub/../b.png", srv.pagePath + "b.png", true},
+		{"../c.png", "/c.png", true},
+		{"../../c.png", "/c.png", true},
+		{"/abs/d.png", "/abs/d.png", true},
+		{".", srv.pagePath, true},
+		{"https://example.com/e.png", "", false},
+		{"data:image/png;base64,AAAA", "", false},
+		{"#top", "", false},
+	}
+	for _, tt := range tests {
+		got, local := srv.resolve(tt.ref)
+		if got != tt.want || local != tt.local {
+			t.Errorf("resolve(%q) = %q, %v, want %q, %v", tt.ref, got, local, tt.want, tt.local)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileURL, err := url.Parse(srv.BaseFileURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileURL.Scheme != "file" || !strings.HasSuffix(fileURL.Path, "/") || filepath.Clean(filepath.FromSlash(strings.TrimPrefix(fileURL.Path, "/"))) != filepath.Clean(strings.TrimPrefix(wd, "/")) {
+		t.Errorf("BaseFileURL() = %q for directory %q", srv.BaseFileURL(), wd)
+	}
+}
+
+func TestCheckAssets(t *testing.T) {
+	docs := t.TempDir()
+	srv, err := serveDocument("", docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	for _, ref := range []string{"pic.png", "../img/x.png", "../style.css"} {
+		fetch(t, srv.URL, ref)
+	}
+
+	broken := []string{"./pic.png", "../img/x.png", "https://example.com/remote.png"}
+	want := []string{
+		"asset not found: pic.png",
+		"asset outside the document directory, not loaded: ../img/x.png",
+		"asset not found: https://example.com/remote.png",
+		"asset outside the document directory, not loaded: /style.css",
+	}
+	c := New(Options{})
+	if err := c.checkAssets(srv, broken); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.Warnings(), want) {
+		t.Errorf("warnings = %q, want %q", c.Warnings(), want)
+	}
+
+	c = New(Options{FailOnMissingAssets: true})
+	err = c.checkAssets(srv, broken)
+	wantErr := "missing assets: pic.png, ../img/x.png (outside the document directory), https://example.com/remote.png, /style.css (outside the document directory)"
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("got error %v, want %q", err, wantErr)
+	}
+}
@@ -0,0 +1,275 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/inspector"
+	"github.com/chromedp/chromedp"
+)
+
+// errTabCrashed is returned when the tab rendering a document crashes
+var errTabCrashed = errors.New("browser tab crashed")
+
+// allocator creates the context in which chromedp launches or connects to
+// the browser
+type allocator func(context.Context) (context.Context, context.CancelFunc)
+
+// newAllocator returns the allocator for the browser settings in opts
+func newAllocator(opts Options) allocator {
+	if opts.RemoteDebuggingURL != "" {
+		return func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return chromedp.NewRemoteAllocator(ctx, opts.RemoteDebuggingURL)
+		}
+	}
+	allocOpts := execAllocatorOptions(opts)
+	return func(ctx context.Context) (context.Context, context.CancelFunc) {
+		return chromedp.NewExecAllocator(ctx, allocOpts...)
+	}
+}
+
+// execAllocatorOptions adds the executable and flags in opts to chromedp's
+// default options. Flags are given as "name" or "name=value", with or
+// without leading dashes.
+func execAllocatorOptions(opts Options) []chromedp.ExecAllocatorOption {
+	allocOpts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	if opts.ChromePath != "" {
+		allocOpts = append(allocOpts, chromedp.ExecPath(opts.ChromePath))
+	}
+	for _, flag := range opts.ChromeFlags {
+		allocOpts = append(allocOpts, chromedp.Flag(chromeFlag(flag)))
+	}
+	return allocOpts
+}
+
+// chromeFlag splits a browser flag given as "name" or "name=value", with or
+// without leading dashes, into its name and the value chromedp expects:
+// the string after the first "=", or true for a switch
+func chromeFlag(flag string) (string, interface{}) {
+	name, value, hasValue := strings.Cut(strings.TrimLeft(flag, "-"), "=")
+	if !hasValue {
+		return name, true
+	}
+	return name, value
+}
+
+// startBrowser launches or connects to the browser of a new chromedp
+// context, explaining the usual reasons when that fails
+func startBrowser(ctx context.Context, opts Options) error {
+	if opts.RemoteDebuggingURL != "" {
+		if err := checkLocalBrowser(opts.RemoteDebuggingURL); err != nil {
+			return err
+		}
+	}
+
+	// Running no actions starts the browser on its initial tab
+	err := chromedp.Run(ctx)
+	switch {
+	case err == nil:
+		return nil
+	case opts.RemoteDebuggingURL != "":
+		return fmt.Errorf("failed to connect to browser at %s: %w", opts.RemoteDebuggingURL, err)
+	case errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist):
+		if opts.ChromePath != "" {
+			return fmt.Errorf("browser not found at %s: %w", opts.ChromePath, err)
+		}
+		return errors.New("no Chrome or Chromium browser found; install one, give its path with --chrome-path, or connect to a running browser with --remote-debugging-url")
+	default:
+		return fmt.Errorf("failed to start browser: %w", err)
+	}
+}
+
+// checkLocalBrowser fails early for a remote browser on another host, which
+// can't reach the document server listening on 127.0.0.1
+func checkLocalBrowser(debuggingURL string) error {
+	u, err := url.Parse(debuggingURL)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("invalid remote debugging URL %q", debuggingURL)
+	}
+	if u.Hostname() == "localhost" {
+		return nil
+	}
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil {
+		return fmt.Errorf("failed to resolve browser host: %w", err)
+	}
+	local, _ := net.InterfaceAddrs()
+	for _, ip := range ips {
+		if ip.IsLoopback() {
+			return nil
+		}
+		for _, addr := range local {
+			if n, ok := addr.(*net.IPNet); ok && n.IP.Equal(ip) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("the browser at %s runs on another host and can't load documents, which are served on 127.0.0.1; run it on this machine or in a container sharing its network", debuggingURL)
+}
+
+// BrowserPool keeps a single Chrome instance running and opens a tab for
+// every conversion, so that many documents can be converted without paying
+// for a browser startup each time. It is safe for concurrent use; each
+// goroutine should use its own Converter created with NewWithBrowser.
+type BrowserPool struct {
+	opts     Options // Browser settings
+	allocate allocator
+	tabs     chan struct{} // Semaphore limiting concurrent tabs
+
+	mu            sync.Mutex
+	allocCancel   context.CancelFunc
+	browserCtx    context.Context
+	browserCancel context.CancelFunc
+	closed        bool
+}
+
+// NewBrowserPool starts Chrome and returns a pool allowing up to size
+// concurrent tabs. Allocator options default to chromedp's. The pool must be
+// closed with Close.
+func NewBrowserPool(size int, allocOpts ...chromedp.ExecAllocatorOption) (*BrowserPool, error) {
+	if len(allocOpts) == 0 {
+		allocOpts = chromedp.DefaultExecAllocatorOptions[:]
+	}
+	return newBrowserPool(size, Options{}, func(ctx context.Context) (context.Context, context.CancelFunc) {
+		return chromedp.NewExecAllocator(ctx, allocOpts...)
+	})
+}
+
+// NewBrowserPoolWithOptions is like NewBrowserPool, but launches the browser
+// given by the ChromePath and ChromeFlags options, or connects to the one at
+// RemoteDebuggingURL. A remote browser is left running when the pool is
+// closed.
+func NewBrowserPoolWithOptions(size int, opts Options) (*BrowserPool, error) {
+	return newBrowserPool(size, opts, newAllocator(opts))
+}
+
+func newBrowserPool(size int, opts Options, allocate allocator) (*BrowserPool, error) {
+	if size < 1 {
+		size = 1
+	}
+
+	p := &BrowserPool{
+		opts:     opts,
+		allocate: allocate,
+		tabs:     make(chan struct{}, size),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// start launches the browser. The caller must hold p.mu.
+func (p *BrowserPool) start() error {
+	allocCtx, allocCancel := p.allocate(context.Background())
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+
+	if err := startBrowser(browserCtx, p.opts); err != nil {
+		browserCancel()
+		allocCancel()
+		return err
+	}
+
+	p.allocCancel = allocCancel
+	p.browserCtx = browserCtx
+	p.browserCancel = browserCancel
+	return nil
+}
+
+// stop shuts the browser down. The caller must hold p.mu.
+func (p *BrowserPool) stop() {
+	if p.browserCancel != nil {
+		p.browserCancel()
+		p.allocCancel()
+	}
+	p.browserCtx, p.browserCancel, p.allocCancel = nil, nil, nil
+}
+
+// alive reports whether the browser is still connected. The caller must
+// hold p.mu.
+func (p *BrowserPool) alive() bool {
+	if p.browserCtx == nil || p.browserCtx.Err() != nil {
+		return false
+	}
+	c := chromedp.FromContext(p.browserCtx)
+	if c == nil || c.Browser == nil {
+		return false
+	}
+	select {
+	case <-c.Browser.LostConnection:
+		return false
+	default:
+		return true
+	}
+}
+
+// newTab waits for a free slot and opens a tab, restarting the browser if
+// it has gone away. Cancelling the returned context closes the tab and
+// frees the slot.
+func (p *BrowserPool) newTab() (context.Context, context.CancelFunc, error) {
+	p.tabs <- struct{}{}
+	release := func() { <-p.tabs }
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		release()
+		return nil, nil, errors.New("browser pool is closed")
+	}
+	if !p.alive() {
+		p.stop()
+		if err := p.start(); err != nil {
+			p.mu.Unlock()
+			release()
+			return nil, nil, err
+		}
+	}
+	tabCtx, tabCancel := chromedp.NewContext(p.browserCtx)
+	p.mu.Unlock()
+
+	return tabCtx, func() {
+		tabCancel()
+		release()
+	}, nil
+}
+
+// Close shuts down the browser. Conversions still running are cancelled.
+func (p *BrowserPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	var err error
+	if p.alive() && p.opts.RemoteDebuggingURL == "" {
+		// Let Chrome exit gracefully before the allocator kills it
+		err = chromedp.Cancel(p.browserCtx)
+	}
+	p.stop()
+	return err
+}
+
+// watchCrash cancels a tab's context with errTabCrashed if the renderer
+// crashes, so the conversion fails fast instead of waiting for the timeout
+func watchCrash(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if _, ok := ev.(*inspector.EventTargetCrashed); ok {
+			cancel(errTabCrashed)
+		}
+	})
+	return ctx, func() { cancel(context.Canceled) }
+}
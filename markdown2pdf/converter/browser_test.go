@@ -0,0 +1,168 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+func TestCheckLocalBrowser(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr string
+	}{
+		{"ws://127.0.0.1:9222/devtools/browser/abc", ""},
+		{"http://localhost:9222", ""},
+		{"ws://[::1]:9222", ""},
+		{"ws://192.0.2.1:9222", "runs on another host"},
+		{"127.0.0.1:9222", "invalid remote debugging URL"},
+	}
+	for _, tt := range tests {
+		err := checkLocalBrowser(tt.url)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tt.url, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: got error %v, want %q", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestChromeFlag(t *testing.T) {
+	tests := []struct {
+		flag  string
+		name  string
+		value interface{}
+	}{
+		{"no-sandbox", "no-sandbox", true},
+		{"--no-sandbox", "no-sandbox", true},
+		{"--window-size=1280,800", "window-size", "1280,800"},
+		{"proxy-server=http://127.0.0.1:3128", "proxy-server", "http://127.0.0.1:3128"},
+		{"--js-flags=--max-old-space-size=4096", "js-flags", "--max-old-space-size=4096"},
+		{"--lang=", "lang", ""},
+	}
+	for _, tt := range tests {
+		name, value := chromeFlag(tt.flag)
+		if name != tt.name || value != tt.value {
+			t.Errorf("%s: got %q = %#v, want %q = %#v", tt.flag, name, value, tt.name, tt.value)
+		}
+	}
+}
+
+// newTestPool starts a pool of size tabs, skipping the test when no browser
+// is installed
+func newTestPool(t *testing.T, size int) *BrowserPool {
+	t.Helper()
+	// Containers often run tests as root, where Chrome needs no sandbox
+	p, err := NewBrowserPoolWithOptions(size, Options{ChromeFlags: []string{"no-sandbox"}})
+	if err != nil {
+		if strings.Contains(err.Error(), "no Chrome or Chromium browser found") {
+			t.Skip(err)
+		}
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+// convertInPool converts a one-heading document in a tab of p
+func convertInPool(p *BrowserPool, i int) error {
+	var buf bytes.Buffer
+	if err := NewWithBrowser(Options{}, p).ConvertTo([]byte(fmt.Sprintf("# Document %d\n", i)), &buf); err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		return fmt.Errorf("document %d is not a PDF", i)
+	}
+	return nil
+}
+
+func TestBrowserPool(t *testing.T) {
+	const size, documents = 2, 6
+	p := newTestPool(t, size)
+
+	// More conversions than tabs wait for each other
+	var wg sync.WaitGroup
+	errs := make([]error, documents)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = convertInPool(p, i)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("document %d: %v", i, err)
+		}
+	}
+	if n := len(p.tabs); n != 0 {
+		t.Errorf("%d tabs still taken", n)
+	}
+
+	// A crashed tab is reported and leaves the browser usable
+	tabCtx, closeTab, err := p.newTab()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stopWatching := watchCrash(tabCtx)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	chromedp.Run(ctx, chromedp.Navigate("chrome://crash"))
+	<-ctx.Done()
+	if cause := context.Cause(ctx); !errors.Is(cause, errTabCrashed) {
+		t.Errorf("crashed tab: got %v", cause)
+	}
+	cancel()
+	stopWatching()
+	closeTab()
+	if err := convertInPool(p, 0); err != nil {
+		t.Errorf("after a tab crash: %v", err)
+	}
+
+	// A browser that went away is started again
+	p.mu.Lock()
+	p.stop()
+	p.mu.Unlock()
+	if err := convertInPool(p, 0); err != nil {
+		t.Errorf("after the browser went away: %v", err)
+	}
+}
+
+func TestBrowserPoolCloseDuringConversions(t *testing.T) {
+	const size, documents = 2, 6
+	p := newTestPool(t, size)
+
+	done := make(chan error, documents)
+	for i := 0; i < documents; i++ {
+		go func(i int) { done <- convertInPool(p, i) }(i)
+	}
+
+	// Close once every tab is taken and the other conversions are waiting
+	deadline := time.Now().Add(30 * time.Second)
+	for len(p.tabs) < size && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+
+	for i := 0; i < documents; i++ {
+		select {
+		case <-done:
+			// Conversions finish or fail, but don't hang
+		case <-time.After(30 * time.Second):
+			t.Fatalf("%d conversions still running after Close", documents-i)
+		}
+	}
+	if err := convertInPool(p, 0); err == nil || !strings.Contains(err.Error(), "browser pool is closed") {
+		t.Errorf("after Close: got error %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package converter
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:generate sh bundled/fetch.sh
+
+// bundled holds the third-party scripts, stylesheets and fonts that
+// documents may need: KaTeX for math and Mermaid for diagrams. The document
+// server serves them under bundledPath, so rendering works without network
+// access. The files are downloaded by go generate and must be present when
+// building.
+//
+//go:embed bundled
+var bundled embed.FS
+
+// bundledPath is the document server path of the bundled files
+const bundledPath = "/_markdown2pdf/"
+
+// katexScript is the KaTeX script within the bundle
+const katexScript = "katex/katex.min.js"
+
+// bundledFS returns the bundled files rooted at the bundle directory
+func bundledFS() fs.FS {
+	sub, err := fs.Sub(bundled, "bundled")
+	if err != nil {
+		panic(err) // The directory is embedded, so this can't happen
+	}
+	return sub
+}
+
+// isBundled reports whether a file was bundled into this build
+func isBundled(name string) bool {
+	_, err := fs.Stat(bundledFS(), name)
+	return err == nil
+}
@@ -0,0 +1,46 @@
+#!/bin/sh
+# Downloads the third-party files embedded into the converter. Run through
+# "go generate ./..." from the module root, or directly from this directory's
+# parent. Requires curl and tar. Packages already present at the pinned
+# version are not downloaded again, so rebuilding works offline.
+set -eu
+
+KATEX_VERSION=0.16.11
+MERMAID_VERSION=10.9.1
+
+cd "$(dirname "$0")"
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+# fetch downloads an npm package and unpacks it into $tmp/<name>
+fetch() {
+	mkdir -p "$tmp/$1"
+	curl -fsSL "https://registry.npmjs.org/$1/-/$1-$2.tgz" | tar -xz -C "$tmp/$1"
+}
+
+# current reports whether package $1 is present at version $2
+current() {
+	[ -s "$1/$1.min.js" ] && [ "$(cat "$1/VERSION" 2>/dev/null)" = "$2" ]
+}
+
+# KaTeX: the script, the stylesheet and the woff2 fonts it references, the
+# format Chrome picks
+if ! current katex "$KATEX_VERSION"; then
+	fetch katex "$KATEX_VERSION"
+	rm -rf katex/fonts katex/VERSION
+	mkdir -p katex/fonts
+	cp "$tmp/katex/package/dist/katex.min.js" "$tmp/katex/package/dist/katex.min.css" katex/
+	cp "$tmp/katex/package/dist/fonts/"*.woff2 katex/fonts/
+	cp "$tmp/katex/package/LICENSE" katex/LICENSE
+	echo "$KATEX_VERSION" >katex/VERSION
+fi
+
+# Mermaid: the self-contained browser build
+if ! current mermaid "$MERMAID_VERSION"; then
+	fetch mermaid "$MERMAID_VERSION"
+	mkdir -p mermaid
+	rm -f mermaid/VERSION
+	cp "$tmp/mermaid/package/dist/mermaid.min.js" mermaid/
+	cp "$tmp/mermaid/package/LICENSE" mermaid/LICENSE
+	echo "$MERMAID_VERSION" >mermaid/VERSION
+fi
@@ -3,12 +3,19 @@ package converter
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/chromedp"
 	"github.com/yuin/goldmark"
 	highlighting "github.com/yuin/goldmark-highlighting/v2"
@@ -36,57 +43,218 @@ type Options struct {
 
 	// Custom CSS to apply
 	CustomCSS string
+
+	// Syntax highlighting style for code blocks (chroma style name)
+	CodeStyle string
+
+	// Fail instead of warning when images or other local assets are missing
+	FailOnMissingAssets bool
+
+	// HTML templates for the page header and footer. The placeholders
+	// {{title}}, {{date}}, {{page}} and {{pages}} are replaced on every page.
+	HeaderTemplate string
+	FooterTemplate string
+
+	// Add a "page / pages" footer when no footer template is given
+	PageNumbers bool
+
+	// Insert a table of contents with page numbers at the [TOC] marker, or
+	// at the start of the document, listing headings up to TOCDepth
+	TOC      bool
+	TOCDepth int
+
+	// Render the front matter title, author and date at the top of the
+	// document
+	TitleBlock bool
+
+	// Chrome or Chromium executable, found on the PATH when empty
+	ChromePath string
+
+	// Extra browser command line flags, as "name" or "name=value"
+	ChromeFlags []string
+
+	// DevTools URL of an already running browser to use instead of
+	// launching one, e.g. ws://127.0.0.1:9222 or http://127.0.0.1:9222
+	RemoteDebuggingURL string
+
+	// Maximum time to render one document, DefaultTimeout when zero
+	Timeout time.Duration
 }
 
+// DefaultCodeStyle is the chroma style used when Options.CodeStyle is empty
+const DefaultCodeStyle = "github"
+
+// DefaultTimeout is the rendering time limit used when Options.Timeout is
+// zero
+const DefaultTimeout = 60 * time.Second
+
 // Converter handles Markdown to PDF conversion
 type Converter struct {
 	opts Options
+
+	// Directory used to resolve relative images and links
+	baseDir string
+
+	// Front matter of the document being converted, if any
+	meta *FrontMatter
+
+	// Non-fatal problems encountered during conversion
+	warnings []string
+
+	// Shared browser, or nil to launch one per conversion
+	pool *BrowserPool
+
+	// Whether the document being converted contains math or diagrams
+	hasMath     bool
+	hasDiagrams bool
 }
 
-// New creates a new Converter with the given options
+// New creates a new Converter with the given options. Each conversion
+// launches and shuts down its own browser.
 func New(opts Options) *Converter {
 	return &Converter{opts: opts}
 }
 
+// NewWithBrowser creates a new Converter that renders in tabs of a shared
+// browser. A Converter must not be used by several goroutines at once, but
+// any number of Converters may share a pool.
+func NewWithBrowser(opts Options, pool *BrowserPool) *Converter {
+	return &Converter{opts: opts, pool: pool}
+}
+
 // ConvertFile reads a Markdown file and converts it to PDF
 func (c *Converter) ConvertFile(inputPath, outputPath string) error {
+	var buf bytes.Buffer
+	if err := c.ConvertFileTo(inputPath, &buf); err != nil {
+		return err
+	}
+	return writePDF(outputPath, buf.Bytes())
+}
+
+// ConvertFileTo reads a Markdown file and writes the PDF to w
+func (c *Converter) ConvertFileTo(inputPath string, w io.Writer) error {
 	content, err := os.ReadFile(inputPath)
 	if err != nil {
 		return fmt.Errorf("failed to read input file: %w", err)
 	}
 
-	return c.Convert(content, outputPath)
+	return c.convert(content, filepath.Dir(inputPath), w)
+}
+
+// ConvertReader reads Markdown from r and writes the PDF to w. Relative
+// image paths resolve against the current directory.
+func (c *Converter) ConvertReader(r io.Reader, w io.Writer) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	return c.ConvertTo(content, w)
+}
+
+// Warnings returns the non-fatal problems found by the last conversion
+func (c *Converter) Warnings() []string {
+	return c.warnings
+}
+
+// warn records a non-fatal conversion problem
+func (c *Converter) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
 }
 
 // Convert converts Markdown content to PDF
 func (c *Converter) Convert(markdown []byte, outputPath string) error {
+	var buf bytes.Buffer
+	if err := c.ConvertTo(markdown, &buf); err != nil {
+		return err
+	}
+	return writePDF(outputPath, buf.Bytes())
+}
+
+// writePDF writes a rendered PDF to a file
+func writePDF(outputPath string, pdf []byte) error {
+	if err := os.WriteFile(outputPath, pdf, 0644); err != nil {
+		return fmt.Errorf("failed to write PDF file: %w", err)
+	}
+	return nil
+}
+
+// ConvertTo converts Markdown content to PDF and writes it to w. Nothing is
+// written if the conversion fails. Relative image paths resolve against the
+// current directory.
+func (c *Converter) ConvertTo(markdown []byte, w io.Writer) error {
+	return c.convert(markdown, "", w)
+}
+
+// convert converts Markdown content whose relative references resolve
+// against baseDir, or the current directory when it is empty
+func (c *Converter) convert(markdown []byte, baseDir string, w io.Writer) error {
+	c.baseDir = baseDir
+	c.warnings = nil
+
+	// Front matter overrides apply to this document only
+	defer func(opts Options) { c.opts = opts }(c.opts)
+
+	meta, body, err := parseFrontMatter(markdown)
+	if err != nil {
+		return err
+	}
+	c.meta = meta
+	if meta != nil {
+		if err := c.applyFrontMatter(meta); err != nil {
+			return err
+		}
+	}
+
 	// Convert Markdown to HTML
-	htmlContent, err := c.markdownToHTML(markdown)
+	htmlContent, err := c.markdownToHTML(body)
 	if err != nil {
 		return fmt.Errorf("failed to convert markdown to HTML: %w", err)
 	}
 
 	// Convert HTML to PDF using Chrome
-	if err := c.htmlToPDF(htmlContent, outputPath); err != nil {
+	pdf, err := c.htmlToPDF(htmlContent)
+	if err != nil {
 		return fmt.Errorf("failed to convert HTML to PDF: %w", err)
 	}
 
+	if _, err := w.Write(pdf); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+
 	return nil
 }
 
 // markdownToHTML converts Markdown content to HTML
 func (c *Converter) markdownToHTML(markdown []byte) (string, error) {
+	codeStyle := c.opts.CodeStyle
+	if codeStyle == "" {
+		codeStyle = DefaultCodeStyle
+	}
+
+	extensions := []goldmark.Extender{
+		extension.GFM, // GitHub Flavored Markdown
+		extension.Table,
+		extension.Strikethrough,
+		extension.TaskList,
+		extension.Footnote,
+		highlighting.NewHighlighting(
+			highlighting.WithStyle(codeStyle),
+		),
+	}
+	if c.opts.TOC {
+		depth := c.opts.TOCDepth
+		if depth <= 0 {
+			depth = DefaultTOCDepth
+		}
+		extensions = append(extensions, &tocExtension{Depth: depth})
+	}
+	mathExt := &mathExtension{}
+	mermaidExt := &mermaidExtension{}
+	extensions = append(extensions, mathExt, mermaidExt)
+
 	// Create goldmark instance with extensions
 	md := goldmark.New(
-		goldmark.WithExtensions(
-			extension.GFM, // GitHub Flavored Markdown
-			extension.Table,
-			extension.Strikethrough,
-			extension.TaskList,
-			highlighting.NewHighlighting(
-				highlighting.WithStyle("github"),
-			),
-		),
+		goldmark.WithExtensions(extensions...),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
 		),
@@ -102,6 +270,15 @@ func (c *Converter) markdownToHTML(markdown []byte) (string, error) {
 		return "", err
 	}
 
+	c.hasMath = mathExt.Found
+	if c.hasMath && !isBundled(katexScript) {
+		c.warn("math is shown as TeX source because KaTeX is not bundled in this build (run go generate ./... and rebuild)")
+	}
+	c.hasDiagrams = mermaidExt.Found
+	if c.hasDiagrams && !isBundled(mermaidScript) {
+		c.warn("Mermaid diagrams are shown as code because Mermaid is not bundled in this build (run go generate ./... and rebuild)")
+	}
+
 	// Wrap in full HTML document with styling
 	html := c.wrapHTML(buf.String())
 	return html, nil
@@ -226,6 +403,82 @@ func (c *Converter) wrapHTML(content string) string {
 		.task-list-item input {
 			margin-right: 0.5em;
 		}
+		.title-block {
+			text-align: center;
+			margin-bottom: 32px;
+		}
+		.title-block .title {
+			font-size: 2.5em;
+			font-weight: 600;
+			line-height: 1.25;
+			margin-bottom: 8px;
+		}
+		.title-block .subtitle {
+			font-size: 1.25em;
+			color: #6a737d;
+		}
+		.title-block .author, .title-block .date {
+			margin-bottom: 4px;
+		}
+		.toc {
+			margin-bottom: 24px;
+		}
+		.toc ul {
+			list-style: none;
+			margin: 0;
+			padding-left: 1.5em;
+		}
+		.toc > ul {
+			padding-left: 0;
+		}
+		.toc li {
+			margin: 0;
+		}
+		.toc a {
+			display: flex;
+			color: #333;
+		}
+		.toc a::after {
+			content: "";
+			order: 1;
+			flex: 1;
+			margin: 0 0.4em 0.3em;
+			border-bottom: 1px dotted #c0c4c8;
+		}
+		.toc-page {
+			order: 2;
+		}
+		.math-display {
+			display: block;
+			margin: 0 0 16px 0;
+		}
+		.mermaid {
+			text-align: center;
+			margin-bottom: 16px;
+			break-inside: avoid;
+		}
+		.mermaid svg {
+			max-width: 100%;
+			height: auto;
+		}
+		.footnote-ref {
+			text-decoration: none;
+		}
+		.footnotes {
+			font-size: 0.875em;
+			color: #444;
+		}
+		.footnotes hr {
+			width: 33%;
+			height: 1px;
+			margin: 32px 0 16px;
+		}
+		.footnotes li p {
+			margin-bottom: 8px;
+		}
+		.footnote-backref {
+			text-decoration: none;
+		}
 	`
 
 	customCSS := ""
@@ -233,12 +486,53 @@ func (c *Converter) wrapHTML(content string) string {
 		customCSS = c.opts.CustomCSS
 	}
 
+	title := "Document"
+	lang := "en"
+	var head strings.Builder
+	if c.meta != nil {
+		if c.meta.Title != "" {
+			title = c.meta.Title
+		}
+		if c.meta.Lang != "" {
+			lang = c.meta.Lang
+		}
+		writeMeta := func(name, value string) {
+			if value != "" {
+				head.WriteString(fmt.Sprintf("\t<meta name=\"%s\" content=\"%s\">\n", name, escapeHTML(value)))
+			}
+		}
+		writeMeta("author", strings.Join(c.meta.Author, ", "))
+		writeMeta("description", c.meta.Subject)
+		writeMeta("keywords", strings.Join(c.meta.Keywords, ", "))
+		if c.opts.TitleBlock {
+			content = c.titleBlock() + content
+		}
+	}
+	if c.hasMath {
+		if isBundled(katexScript) {
+			head.WriteString(fmt.Sprintf("\t<link rel=\"stylesheet\" href=\"%skatex/katex.min.css\">\n", bundledPath))
+			head.WriteString(fmt.Sprintf("\t<script src=\"%s%s\"></script>\n", bundledPath, katexScript))
+		} else {
+			// Without KaTeX, show the source the way code is shown
+			defaultCSS += `
+		.math {
+			font-family: 'SFMono-Regular', Consolas, 'Liberation Mono', Menlo, monospace;
+			font-size: 85%;
+			white-space: pre-wrap;
+		}
+	`
+		}
+	}
+	if c.hasDiagrams && isBundled(mermaidScript) {
+		head.WriteString(fmt.Sprintf("\t<script src=\"%s%s\"></script>\n", bundledPath, mermaidScript))
+	}
+
 	return fmt.Sprintf(`<!DOCTYPE html>
-<html lang="en">
+<html lang="%s">
 <head>
 	<meta charset="UTF-8">
 	<meta name="viewport" content="width=device-width, initial-scale=1.0">
-	<title>Document</title>
+%s	<title>%s</title>
 	<style>
 %s
 %s
@@ -247,17 +541,99 @@ func (c *Converter) wrapHTML(content string) string {
 <body>
 %s
 </body>
-</html>`, defaultCSS, customCSS, content)
+</html>`, escapeHTML(lang), head.String(), escapeHTML(title), defaultCSS, customCSS, content)
 }
 
-// htmlToPDF converts HTML content to PDF using Chrome headless
-func (c *Converter) htmlToPDF(htmlContent, outputPath string) error {
-	// Create context with timeout
-	ctx, cancel := chromedp.NewContext(context.Background())
-	defer cancel()
+// titleBlock renders the front matter title, authors and date
+func (c *Converter) titleBlock() string {
+	var block strings.Builder
+	block.WriteString("<header class=\"title-block\">\n")
+	if c.meta.Title != "" {
+		block.WriteString(fmt.Sprintf("<p class=\"title\">%s</p>\n", escapeHTML(c.meta.Title)))
+	}
+	if c.meta.Subject != "" {
+		block.WriteString(fmt.Sprintf("<p class=\"subtitle\">%s</p>\n", escapeHTML(c.meta.Subject)))
+	}
+	if len(c.meta.Author) > 0 {
+		block.WriteString(fmt.Sprintf("<p class=\"author\">%s</p>\n", escapeHTML(strings.Join(c.meta.Author, ", "))))
+	}
+	if c.meta.Date != "" {
+		block.WriteString(fmt.Sprintf("<p class=\"date\">%s</p>\n", escapeHTML(c.meta.Date)))
+	}
+	block.WriteString("</header>\n")
+	return block.String()
+}
+
+// htmlToPDF converts HTML content to PDF using Chrome headless. The page is
+// served from a loopback server together with the Markdown file's directory
+// so that relative file references resolve.
+func (c *Converter) htmlToPDF(htmlContent string) ([]byte, error) {
+	srv, err := serveDocument(htmlContent, c.baseDir)
+	if err != nil {
+		return nil, err
+	}
+	defer srv.Close()
+
+	warnings := len(c.warnings)
+	pdfBuf, err := c.renderPDF(srv)
+	if errors.Is(err, errTabCrashed) {
+		// A crashed renderer only takes its tab down, so try once more in a
+		// fresh one
+		c.warnings = c.warnings[:warnings]
+		pdfBuf, err = c.renderPDF(srv)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("chrome operation failed: %w", err)
+	}
+
+	if c.meta != nil {
+		withInfo, err := setPDFInfo(pdfBuf, c.meta)
+		if err != nil {
+			c.warn("failed to set PDF metadata: %v", err)
+		} else {
+			pdfBuf = withInfo
+		}
+	}
+
+	return pdfBuf, nil
+}
+
+// tabContext returns a context for a new browser tab: a tab of the shared
+// browser when the converter has a pool, or a browser of its own otherwise
+func (c *Converter) tabContext() (context.Context, context.CancelFunc, error) {
+	if c.pool != nil {
+		return c.pool.newTab()
+	}
+	allocCtx, allocCancel := newAllocator(c.opts)(context.Background())
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	if err := startBrowser(ctx, c.opts); err != nil {
+		cancel()
+		allocCancel()
+		return nil, nil, err
+	}
+	return ctx, func() {
+		cancel()
+		allocCancel()
+	}, nil
+}
+
+// renderPDF loads the served document in a browser tab and prints it
+func (c *Converter) renderPDF(srv *documentServer) ([]byte, error) {
+	tabCtx, closeTab, err := c.tabContext()
+	if err != nil {
+		return nil, err
+	}
+	defer closeTab()
+
+	tabCtx, stopWatching := watchCrash(tabCtx)
+	defer stopWatching()
 
 	// Set timeout
-	ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
+	timeout := c.opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(tabCtx, timeout)
 	defer cancel()
 
 	// Get paper dimensions
@@ -269,40 +645,247 @@ func (c *Converter) htmlToPDF(htmlContent, outputPath string) error {
 		WithLandscape(c.opts.Landscape).
 		WithPaperWidth(width).
 		WithPaperHeight(height).
-		WithMarginTop(c.opts.MarginTop / 25.4).      // Convert mm to inches
+		WithMarginTop(c.opts.MarginTop / 25.4). // Convert mm to inches
 		WithMarginBottom(c.opts.MarginBottom / 25.4).
 		WithMarginLeft(c.opts.MarginLeft / 25.4).
-		WithMarginRight(c.opts.MarginRight / 25.4)
+		WithMarginRight(c.opts.MarginRight / 25.4).
+		WithGenerateTaggedPDF(true).
+		WithGenerateDocumentOutline(true) // Bookmarks from the heading tree
+
+	if header, footer, ok := c.headerFooterTemplates(); ok {
+		printParams = printParams.
+			WithDisplayHeaderFooter(true).
+			WithHeaderTemplate(header).
+			WithFooterTemplate(footer)
+	}
 
 	var pdfBuf []byte
+	var brokenImages []string
+	var rewritten bool
 
 	// Run Chrome tasks
 	if err := chromedp.Run(ctx,
-		chromedp.Navigate("about:blank"),
+		chromedp.Navigate(srv.URL),
+		chromedp.ActionFunc(c.typesetMath),
+		chromedp.ActionFunc(c.renderDiagrams),
+		chromedp.Evaluate(waitForAssetsScript, &brokenImages, awaitPromise),
+		chromedp.Evaluate(fmt.Sprintf(rewriteLinksScript, srv.BaseFileURL()), &rewritten),
 		chromedp.ActionFunc(func(ctx context.Context) error {
-			frameTree, err := page.GetFrameTree().Do(ctx)
-			if err != nil {
-				return err
-			}
-			return page.SetDocumentContent(frameTree.Frame.ID, htmlContent).Do(ctx)
+			return c.checkAssets(srv, brokenImages)
 		}),
+		chromedp.ActionFunc(c.fillTOCPages),
 		chromedp.ActionFunc(func(ctx context.Context) error {
 			var err error
-			pdfBuf, _, err = printParams.Do(ctx)
+			pdfBuf, err = c.printPDF(ctx, printParams)
 			return err
 		}),
 	); err != nil {
-		return fmt.Errorf("chrome operation failed: %w", err)
+		if errors.Is(context.Cause(ctx), errTabCrashed) {
+			return nil, errTabCrashed
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("rendering did not finish within %s: %w", timeout, err)
+		}
+		return nil, err
 	}
 
-	// Write PDF to file
-	if err := os.WriteFile(outputPath, pdfBuf, 0644); err != nil {
-		return fmt.Errorf("failed to write PDF file: %w", err)
+	return pdfBuf, nil
+}
+
+// typesetMath renders the document's math with KaTeX and waits for its
+// fonts before anything is measured or printed. Formulas KaTeX can't parse
+// become warnings.
+func (c *Converter) typesetMath(ctx context.Context) error {
+	if !c.hasMath || !isBundled(katexScript) {
+		return nil
+	}
+	var errs []string
+	if err := chromedp.Evaluate(mathScript, &errs, awaitPromise).Do(ctx); err != nil {
+		return fmt.Errorf("failed to typeset math: %w", err)
+	}
+	for _, e := range errs {
+		c.warn("math: %s", e)
+	}
+	return nil
+}
+
+// renderDiagrams draws the document's Mermaid diagrams before anything is
+// measured or printed. Diagrams Mermaid can't parse fail the conversion.
+func (c *Converter) renderDiagrams(ctx context.Context) error {
+	if !c.hasDiagrams || !isBundled(mermaidScript) {
+		return nil
+	}
+	var errs []mermaidError
+	if err := chromedp.Evaluate(mermaidRenderScript, &errs, awaitPromise).Do(ctx); err != nil {
+		return fmt.Errorf("failed to render diagrams: %w", err)
+	}
+	if len(errs) > 0 {
+		return diagramError(errs)
+	}
+	return nil
+}
+
+// checkAssets reports local files that were requested but don't exist or
+// lie outside the base directory and images that failed to load, as
+// warnings or as an error
+func (c *Converter) checkAssets(srv *documentServer, brokenImages []string) error {
+	var problems, messages []string
+	report := func(problem, message string) {
+		problems = append(problems, problem)
+		messages = append(messages, message)
 	}
 
+	missing, outside := srv.Missing(), srv.Outside()
+	for _, p := range missing {
+		report(p, "asset not found: "+p)
+	}
+
+	// Images are reported by their source rather than the path the
+	// browser requested
+	outsideImages := map[string]bool{}
+	for _, src := range brokenImages {
+		urlPath, local := srv.resolve(src)
+		switch {
+		case local && strings.HasPrefix(urlPath, srv.pagePath) && slices.Contains(missing, strings.TrimPrefix(urlPath, srv.pagePath)):
+			// Reported above
+		case local && slices.Contains(outside, urlPath):
+			outsideImages[urlPath] = true
+			report(src+" (outside the document directory)", "asset outside the document directory, not loaded: "+src)
+		default:
+			report(src, "asset not found: "+src)
+		}
+	}
+	for _, p := range outside {
+		if !outsideImages[p] {
+			report(p+" (outside the document directory)", "asset outside the document directory, not loaded: "+p)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	if c.opts.FailOnMissingAssets {
+		return fmt.Errorf("missing assets: %s", strings.Join(problems, ", "))
+	}
+	for _, m := range messages {
+		c.warn("%s", m)
+	}
 	return nil
 }
 
+// fillTOCPages lays the document out at the printable page width and fills
+// in estimated table of contents page numbers. The estimate divides a
+// heading's position by the page height, so it ignores page breaks; printPDF
+// replaces it with the pages headings are printed on.
+func (c *Converter) fillTOCPages(ctx context.Context) error {
+	if !c.opts.TOC {
+		return nil
+	}
+
+	width, height := c.getPaperDimensions()
+	if c.opts.Landscape {
+		width, height = height, width
+	}
+	const cssPixelsPerInch = 96
+	pageWidth := (width - (c.opts.MarginLeft+c.opts.MarginRight)/25.4) * cssPixelsPerInch
+	pageHeight := (height - (c.opts.MarginTop+c.opts.MarginBottom)/25.4) * cssPixelsPerInch
+
+	if err := emulation.SetEmulatedMedia().WithMedia("print").Do(ctx); err != nil {
+		return err
+	}
+	if err := emulation.SetDeviceMetricsOverride(int64(pageWidth), int64(pageHeight), 1, false).Do(ctx); err != nil {
+		return err
+	}
+
+	var done bool
+	if err := chromedp.Evaluate(fmt.Sprintf(tocPagesScript, pageHeight), &done).Do(ctx); err != nil {
+		return fmt.Errorf("failed to number table of contents: %w", err)
+	}
+
+	return emulation.ClearDeviceMetricsOverride().Do(ctx)
+}
+
+// printPDF prints the document. With a table of contents, the pages its
+// headings were printed on are read back from the PDF's named destinations,
+// and the document is printed again when they differ from the estimates.
+// Corrected numbers are wider at most by a digit, so one correction is
+// enough unless that reflows the table of contents onto another page.
+func (c *Converter) printPDF(ctx context.Context, params *page.PrintToPDFParams) ([]byte, error) {
+	pdf, _, err := params.Do(ctx)
+	if err != nil || !c.opts.TOC {
+		return pdf, err
+	}
+
+	r, err := newPDFReader(pdf)
+	var pages map[string]int
+	if err == nil {
+		pages, err = r.destinationPages()
+	}
+	if err != nil {
+		c.warn("table of contents page numbers are estimates: %v", err)
+		return pdf, nil
+	}
+
+	pagesJSON, err := json.Marshal(pages)
+	if err != nil {
+		return nil, err
+	}
+	var changed bool
+	if err := chromedp.Evaluate(fmt.Sprintf(tocSetPagesScript, pagesJSON), &changed).Do(ctx); err != nil {
+		return nil, fmt.Errorf("failed to number table of contents: %w", err)
+	}
+	if !changed {
+		return pdf, nil
+	}
+	pdf, _, err = params.Do(ctx)
+	return pdf, err
+}
+
+// pageNumbersTemplate is the footer used by Options.PageNumbers
+const pageNumbersTemplate = `{{page}} / {{pages}}`
+
+// Chrome replaces the content of elements with these classes when printing
+var templatePlaceholders = strings.NewReplacer(
+	"{{title}}", `<span class="title"></span>`,
+	"{{date}}", `<span class="date"></span>`,
+	"{{page}}", `<span class="pageNumber"></span>`,
+	"{{pages}}", `<span class="totalPages"></span>`,
+	"{{url}}", `<span class="url"></span>`,
+)
+
+// headerFooterTemplates returns the Chrome header and footer templates and
+// whether headers and footers should be displayed at all
+func (c *Converter) headerFooterTemplates() (header, footer string, ok bool) {
+	header = c.opts.HeaderTemplate
+	footer = c.opts.FooterTemplate
+	if footer == "" && c.opts.PageNumbers {
+		footer = pageNumbersTemplate
+	}
+	if header == "" && footer == "" {
+		return "", "", false
+	}
+
+	// Chrome prints its own default for an empty template, so use an empty
+	// element instead
+	return c.expandTemplate(header), c.expandTemplate(footer), true
+}
+
+// expandTemplate replaces placeholders and wraps the template in a container
+// aligned with the page margins. Chrome renders templates at a tiny default
+// font size, so a readable size is set.
+func (c *Converter) expandTemplate(template string) string {
+	if template == "" {
+		return "<span></span>"
+	}
+	return fmt.Sprintf(`<div style="box-sizing: border-box; width: 100%%; font-size: 9px; color: #6a737d; text-align: center; padding-left: %.2fmm; padding-right: %.2fmm;">%s</div>`,
+		c.opts.MarginLeft, c.opts.MarginRight, templatePlaceholders.Replace(template))
+}
+
+// awaitPromise makes chromedp.Evaluate wait for a returned promise to settle
+func awaitPromise(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+	return p.WithAwaitPromise(true)
+}
+
 // getPaperDimensions returns paper width and height in inches
 func (c *Converter) getPaperDimensions() (width, height float64) {
 	size := strings.ToLower(c.opts.PaperSize)
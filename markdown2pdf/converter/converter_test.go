@@ -0,0 +1,221 @@
+package converter
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/example/shared/frontmatter"
+)
+
+// body converts markdown with c and returns the content of the HTML body
+func body(t *testing.T, c *Converter, markdown string) string {
+	t.Helper()
+	page, err := c.markdownToHTML([]byte(markdown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := strings.Index(page, "<body>")
+	end := strings.LastIndex(page, "</body>")
+	if start < 0 || end < start {
+		t.Fatalf("no body in %q", page)
+	}
+	return page[start+len("<body>") : end]
+}
+
+func TestMarkdownToHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		markdown string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "footnotes",
+			markdown: "A claim[^src] and another[^src].\n\n[^src]: The source.\n",
+			want: []string{
+				`<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup>`,
+				`<div class="footnotes" role="doc-endnotes">`,
+				`<li id="fn:1">`,
+				`class="footnote-backref"`,
+			},
+		},
+		{
+			name:     "inline and display math",
+			markdown: "Inline $x^2$ costs $5 and \\$10.\n\n$$\n\\frac{a}{b}\n$$\n",
+			want: []string{
+				`<span class="math math-inline">x^2</span>`,
+				`<div class="math math-display">\frac{a}{b}`,
+				`costs $5 and $10.`,
+			},
+		},
+		{
+			name:     "table of contents at marker",
+			opts:     Options{TOC: true, TOCDepth: 2},
+			markdown: "# Intro\n\n[TOC]\n\n## Café & more\n\n### Too deep\n",
+			want: []string{
+				"<h1 id=\"intro\">Intro</h1>\n<nav class=\"toc\">",
+				`<a href="#caf--more"><span class="toc-title">Café &amp; more</span><span class="toc-page"></span></a>`,
+			},
+			notWant: []string{`href="#too-deep"`, "[TOC]"},
+		},
+		{
+			name:     "table of contents without marker",
+			opts:     Options{TOC: true},
+			markdown: "Text\n\n# Heading\n",
+			want:     []string{"\n<nav class=\"toc\">"},
+		},
+		{
+			name:     "no table of contents by default",
+			markdown: "[TOC]\n\n# Heading\n",
+			want:     []string{"<p>[TOC]</p>"},
+			notWant:  []string{`class="toc"`},
+		},
+		{
+			name:     "GitHub flavoured extensions",
+			markdown: "| a |\n|---|\n| 1 |\n\n- [x] done\n\n~~gone~~ https://example.com\n",
+			want: []string{
+				"<table>",
+				`<input checked="" disabled="" type="checkbox" />`,
+				"<del>gone</del>",
+				`<a href="https://example.com">https://example.com</a>`,
+			},
+		},
+		{
+			name:     "highlighted code",
+			markdown: "```go\nfunc f() {}\n```\n",
+			want:     []string{`<pre tabindex="0" style="background-color:#fff;">`},
+			notWant:  []string{`class="language-go"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := body(t, New(tt.opts), tt.markdown)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("body lacks %q:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("body has %q:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkdownToHTMLMermaid(t *testing.T) {
+	c := New(Options{})
+	got := body(t, c, "Text\n\n```mermaid\ngraph TD; A-->B\n```\n")
+
+	if !c.hasDiagrams {
+		t.Error("diagram not noticed")
+	}
+	if isBundled(mermaidScript) {
+		if !strings.Contains(got, `<div class="mermaid" data-line="3">`) {
+			t.Errorf("diagram not prepared for Mermaid:\n%s", got)
+		}
+	} else {
+		if !strings.Contains(got, `<code class="language-mermaid">graph TD; A--&gt;B`) {
+			t.Errorf("diagram not shown as code:\n%s", got)
+		}
+		if len(c.Warnings()) != 1 {
+			t.Errorf("got warnings %q, want one about Mermaid", c.Warnings())
+		}
+	}
+}
+
+func TestWrapHTMLFrontMatter(t *testing.T) {
+	c := New(Options{TitleBlock: true})
+	c.meta = &FrontMatter{Metadata: frontmatter.Metadata{
+		Title:  "Q3 <Report>",
+		Author: frontmatter.StringList{"Ada", "Grace"},
+		Lang:   "de",
+	}}
+	page := c.wrapHTML("<p>Text</p>")
+
+	for _, want := range []string{
+		`<html lang="de">`,
+		"<title>Q3 &lt;Report&gt;</title>",
+		`<meta name="author" content="Ada, Grace">`,
+		"<body>\n<header class=\"title-block\">\n<p class=\"title\">Q3 &lt;Report&gt;</p>\n<p class=\"author\">Ada, Grace</p>\n</header>\n<p>Text</p>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+}
+
+func TestHeaderFooterTemplates(t *testing.T) {
+	const style = `box-sizing: border-box; width: 100%; font-size: 9px; color: #6a737d; text-align: center; `
+
+	tests := []struct {
+		name   string
+		opts   Options
+		header string
+		footer string
+		wantOK bool
+	}{
+		{name: "none", opts: Options{MarginLeft: 10}},
+		{
+			name:   "page numbers",
+			opts:   Options{PageNumbers: true, MarginLeft: 10, MarginRight: 25},
+			header: "<span></span>",
+			footer: `<div style="` + style + `padding-left: 10.00mm; padding-right: 25.00mm;"><span class="pageNumber"></span> / <span class="totalPages"></span></div>`,
+			wantOK: true,
+		},
+		{
+			name:   "templates",
+			opts:   Options{HeaderTemplate: "<b>{{title}}</b>", FooterTemplate: "{{date}} {{url}}", PageNumbers: true, MarginLeft: 12.5},
+			header: `<div style="` + style + `padding-left: 12.50mm; padding-right: 0.00mm;"><b><span class="title"></span></b></div>`,
+			footer: `<div style="` + style + `padding-left: 12.50mm; padding-right: 0.00mm;"><span class="date"></span> <span class="url"></span></div>`,
+			wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		header, footer, ok := New(tt.opts).headerFooterTemplates()
+		if header != tt.header || footer != tt.footer || ok != tt.wantOK {
+			t.Errorf("%s: got %q, %q, %v\nwant %q, %q, %v", tt.name, header, footer, ok, tt.header, tt.footer, tt.wantOK)
+		}
+	}
+}
+
+func TestReusedConverterForgetsFileDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "style.css"), []byte("p {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	markdown := "---\ncss: style.css\n---\nText\n"
+	input := filepath.Join(dir, "doc.md")
+	if err := os.WriteFile(input, []byte(markdown), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Conversions stop at launching the browser, after the stylesheet is read
+	c := New(Options{ChromePath: filepath.Join(dir, "no-browser")})
+	convertFile := func() {
+		t.Helper()
+		err := c.ConvertFileTo(input, io.Discard)
+		if err == nil || !strings.Contains(err.Error(), "browser not found") {
+			t.Fatalf("file: got error %v, want a missing browser", err)
+		}
+	}
+
+	// The stylesheet is next to the file, not in the current directory
+	convert := map[string]func() error{
+		"ConvertTo":     func() error { return c.ConvertTo([]byte(markdown), io.Discard) },
+		"ConvertReader": func() error { return c.ConvertReader(strings.NewReader(markdown), io.Discard) },
+		"Convert":       func() error { return c.Convert([]byte(markdown), filepath.Join(dir, "out.pdf")) },
+	}
+	for name, fn := range convert {
+		convertFile()
+		if err := fn(); err == nil || !strings.Contains(err.Error(), "failed to read front matter CSS") {
+			t.Errorf("%s: got error %v, want the stylesheet not found", name, err)
+		}
+	}
+}
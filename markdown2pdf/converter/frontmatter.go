@@ -0,0 +1,106 @@
+package converter
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+
+	"github.com/example/shared/frontmatter"
+)
+
+// FrontMatter is the YAML block at the top of a Markdown document, delimited
+// by "---" lines. Besides document metadata it can override Options for the
+// document; unset overrides keep the converter's options.
+type FrontMatter struct {
+	frontmatter.Metadata `yaml:",inline"`
+
+	// Render the title, author and date at the top of the document
+	TitleBlock *bool `yaml:"title-block"`
+
+	// Option overrides
+	PaperSize       *string  `yaml:"paper-size"`
+	Landscape       *bool    `yaml:"landscape"`
+	Margin          *float64 `yaml:"margin"`
+	MarginTop       *float64 `yaml:"margin-top"`
+	MarginBottom    *float64 `yaml:"margin-bottom"`
+	MarginLeft      *float64 `yaml:"margin-left"`
+	MarginRight     *float64 `yaml:"margin-right"`
+	PrintBackground *bool    `yaml:"print-background"`
+	CodeStyle       *string  `yaml:"code-style"`
+	TOC             *bool    `yaml:"toc"`
+	TOCDepth        *int     `yaml:"toc-depth"`
+	CSS             *string  `yaml:"css"` // Stylesheet path, relative to the document
+}
+
+// parseFrontMatter splits a leading front matter block off markdown. A
+// document without front matter is returned as is, with nil front matter.
+func parseFrontMatter(markdown []byte) (*FrontMatter, []byte, error) {
+	fm := &FrontMatter{}
+	found, body, err := frontmatter.Parse(markdown, fm)
+	if err != nil || !found {
+		return nil, body, err
+	}
+	return fm, body, nil
+}
+
+// applyFrontMatter overrides the converter's options with those set in the
+// front matter
+func (c *Converter) applyFrontMatter(fm *FrontMatter) error {
+	setString(&c.opts.PaperSize, fm.PaperSize)
+	setBool(&c.opts.Landscape, fm.Landscape)
+	if fm.Margin != nil {
+		c.opts.MarginTop = *fm.Margin
+		c.opts.MarginBottom = *fm.Margin
+		c.opts.MarginLeft = *fm.Margin
+		c.opts.MarginRight = *fm.Margin
+	}
+	setFloat(&c.opts.MarginTop, fm.MarginTop)
+	setFloat(&c.opts.MarginBottom, fm.MarginBottom)
+	setFloat(&c.opts.MarginLeft, fm.MarginLeft)
+	setFloat(&c.opts.MarginRight, fm.MarginRight)
+	setBool(&c.opts.PrintBackground, fm.PrintBackground)
+	setString(&c.opts.CodeStyle, fm.CodeStyle)
+	setBool(&c.opts.TOC, fm.TOC)
+	setBool(&c.opts.TitleBlock, fm.TitleBlock)
+	if fm.TOCDepth != nil {
+		c.opts.TOCDepth = *fm.TOCDepth
+	}
+
+	if fm.CSS != nil && *fm.CSS != "" {
+		path := *fm.CSS
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.baseDir, path)
+		}
+		css, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read front matter CSS: %w", err)
+		}
+		c.opts.CustomCSS += "\n" + string(css)
+	}
+
+	return nil
+}
+
+func setString(dst *string, src *string) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setBool(dst *bool, src *bool) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setFloat(dst *float64, src *float64) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// escapeHTML escapes text for use in HTML content and attribute values
+func escapeHTML(text string) string {
+	return html.EscapeString(text)
+}
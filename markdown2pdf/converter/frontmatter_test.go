@@ -0,0 +1,71 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/example/shared/frontmatter"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	fm, body, err := parseFrontMatter([]byte("---\ntitle: Report\nauthor: [Ada, Grace]\ntitle-block: true\nmargin: 20\nmargin-left: 30\ntoc-depth: 2\n---\nBody\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm.Title != "Report" || !reflect.DeepEqual(fm.Author, frontmatter.StringList{"Ada", "Grace"}) {
+		t.Errorf("got metadata %+v", fm.Metadata)
+	}
+	if fm.TitleBlock == nil || !*fm.TitleBlock || fm.Margin == nil || *fm.Margin != 20 ||
+		fm.MarginLeft == nil || *fm.MarginLeft != 30 || fm.TOCDepth == nil || *fm.TOCDepth != 2 {
+		t.Errorf("got overrides %+v", fm)
+	}
+	if string(body) != "\n\n\n\n\n\n\n\nBody\n" {
+		t.Errorf("got body %q", body)
+	}
+
+	// Thematic breaks around text aren't front matter
+	markdown := "---\nSome text\n---\n"
+	if fm, body, err := parseFrontMatter([]byte(markdown)); fm != nil || string(body) != markdown || err != nil {
+		t.Errorf("thematic breaks: got %+v, %q, %v", fm, body, err)
+	}
+}
+
+func TestParseFrontMatterRejectsBadFields(t *testing.T) {
+	_, _, err := parseFrontMatter([]byte("---\nmargin: wide\n---\nBody\n"))
+	if err == nil || !strings.Contains(err.Error(), "invalid front matter") {
+		t.Errorf("got %v, want an invalid front matter error", err)
+	}
+}
+
+func TestApplyFrontMatter(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "print.css"), []byte("h1 { color: red }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fm, _, err := parseFrontMatter([]byte("---\npaper-size: A5\nlandscape: true\nmargin: 20\nmargin-left: 30\ncss: print.css\n---\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(Options{PaperSize: "A4", MarginTop: 10, CustomCSS: "p {}", CodeStyle: "monokai"})
+	c.baseDir = dir
+	if err := c.applyFrontMatter(fm); err != nil {
+		t.Fatal(err)
+	}
+	want := Options{
+		PaperSize:    "A5",
+		Landscape:    true,
+		MarginTop:    20,
+		MarginBottom: 20,
+		MarginLeft:   30,
+		MarginRight:  20,
+		CustomCSS:    "p {}\nh1 { color: red }",
+		CodeStyle:    "monokai",
+	}
+	if !reflect.DeepEqual(c.opts, want) {
+		t.Errorf("got options %+v\nwant %+v", c.opts, want)
+	}
+}
@@ -0,0 +1,86 @@
+package converter
+
+import (
+	"github.com/example/shared/texmath"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// mathExtension adds the renderer for the math parsed by texmath: elements
+// holding the TeX source, which KaTeX typesets in the browser.
+type mathExtension struct {
+	// Found is set once a document containing math has been rendered
+	Found bool
+}
+
+// Extend implements goldmark.Extender
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	texmath.Extension.Extend(m)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&mathRenderer{ext: e}, 500),
+	))
+}
+
+// mathRenderer renders math as elements holding the escaped TeX source
+type mathRenderer struct {
+	ext *mathExtension
+}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(texmath.KindInline, r.renderMath)
+	reg.Register(texmath.KindBlock, r.renderMathBlock)
+}
+
+func (r *mathRenderer) renderMath(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	r.ext.Found = true
+
+	n := node.(*texmath.Inline)
+	class := "math math-inline"
+	if n.Display {
+		class = "math math-display"
+	}
+	w.WriteString(`<span class="` + class + `">`)
+	w.Write(util.EscapeHTML(n.TeX))
+	w.WriteString("</span>")
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *mathRenderer) renderMathBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	r.ext.Found = true
+
+	w.WriteString(`<div class="math math-display">`)
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		w.Write(util.EscapeHTML(segment.Value(source)))
+	}
+	w.WriteString("</div>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// mathScript typesets every math element with KaTeX and waits for its fonts,
+// returning the TeX and message of formulas KaTeX could not parse. Those
+// are rendered with the error highlighted instead.
+const mathScript = `(() => {
+	const errors = [];
+	for (const el of document.querySelectorAll('.math')) {
+		const tex = el.textContent;
+		const options = {displayMode: el.classList.contains('math-display'), throwOnError: true};
+		try {
+			katex.render(tex, el, options);
+		} catch (e) {
+			errors.push(tex.trim() + ': ' + e.message);
+			katex.render(tex, el, {...options, throwOnError: false});
+		}
+	}
+	return document.fonts.ready.then(() => errors);
+})()`
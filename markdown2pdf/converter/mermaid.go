@@ -0,0 +1,172 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// mermaidScript is the Mermaid script within the bundle
+const mermaidScript = "mermaid/mermaid.min.js"
+
+// KindMermaid is the node kind of a Mermaid diagram
+var KindMermaid = ast.NewNodeKind("Mermaid")
+
+// mermaidNode is a ```mermaid fenced code block, rendered as a diagram
+type mermaidNode struct {
+	ast.BaseBlock
+
+	// Source line of the first line of the diagram
+	Line int
+}
+
+// Kind implements ast.Node
+func (n *mermaidNode) Kind() ast.NodeKind {
+	return KindMermaid
+}
+
+// IsRaw implements ast.Node
+func (n *mermaidNode) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node
+func (n *mermaidNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Line": strconv.Itoa(n.Line)}, nil)
+}
+
+// mermaidExtension turns ```mermaid code blocks into diagrams, which
+// Mermaid draws in the browser. Without the Mermaid bundle they stay code.
+type mermaidExtension struct {
+	// Found is set once a document containing diagrams has been rendered
+	Found bool
+}
+
+// Extend implements goldmark.Extender
+func (e *mermaidExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&mermaidTransformer{}, 100),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&mermaidRenderer{ext: e}, 500),
+	))
+}
+
+// mermaidTransformer replaces mermaid code blocks with diagram nodes before
+// code highlighting sees them
+type mermaidTransformer struct{}
+
+// Transform implements parser.ASTTransformer
+func (t *mermaidTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var blocks []*ast.FencedCodeBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if block, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			if strings.EqualFold(string(block.Language(source)), "mermaid") {
+				blocks = append(blocks, block)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, block := range blocks {
+		node := &mermaidNode{}
+		node.SetLines(block.Lines())
+		if lines := block.Lines(); lines.Len() > 0 {
+			node.Line = bytes.Count(source[:lines.At(0).Start], []byte("\n")) + 1
+		} else if info := block.Info; info != nil {
+			node.Line = bytes.Count(source[:info.Segment.Start], []byte("\n")) + 2
+		}
+		block.Parent().ReplaceChild(block.Parent(), block, node)
+	}
+}
+
+// mermaidRenderer renders diagrams as elements holding their escaped
+// source, which the browser replaces with SVG
+type mermaidRenderer struct {
+	ext *mermaidExtension
+}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *mermaidRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMermaid, r.renderMermaid)
+}
+
+func (r *mermaidRenderer) renderMermaid(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	r.ext.Found = true
+
+	n := node.(*mermaidNode)
+	if isBundled(mermaidScript) {
+		fmt.Fprintf(w, `<div class="mermaid" data-line="%d">`, n.Line)
+	} else {
+		w.WriteString(`<pre><code class="language-mermaid">`)
+	}
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		w.Write(util.EscapeHTML(segment.Value(source)))
+	}
+	if isBundled(mermaidScript) {
+		w.WriteString("</div>\n")
+	} else {
+		w.WriteString("</code></pre>\n")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// mermaidError is a diagram Mermaid could not parse
+type mermaidError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// mermaidErrorLine matches the diagram line number in Mermaid's parse
+// errors
+var mermaidErrorLine = regexp.MustCompile(`^Parse error on line (\d+):\s*`)
+
+// diagramError describes Mermaid errors at their Markdown source lines
+func diagramError(errs []mermaidError) error {
+	var messages []string
+	for _, e := range errs {
+		line := e.Line
+		message := strings.TrimSpace(e.Message)
+		if m := mermaidErrorLine.FindStringSubmatch(message); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			line += n - 1
+			message = strings.TrimPrefix(message, m[0])
+		}
+		messages = append(messages, fmt.Sprintf("line %d: %s", line, message))
+	}
+	return fmt.Errorf("invalid Mermaid diagram:\n%s", strings.Join(messages, "\n"))
+}
+
+// mermaidRenderScript draws every diagram as SVG, returning the diagrams
+// that failed with their first source line
+const mermaidRenderScript = `(async () => {
+	mermaid.initialize({startOnLoad: false, securityLevel: 'strict'});
+	const errors = [];
+	let id = 0;
+	for (const el of document.querySelectorAll('div.mermaid')) {
+		const line = Number(el.dataset.line);
+		try {
+			const {svg} = await mermaid.render('mermaid-' + id++, el.textContent);
+			el.innerHTML = svg;
+		} catch (e) {
+			errors.push({line, message: String((e && e.message) || e)});
+		}
+	}
+	return errors;
+})()`
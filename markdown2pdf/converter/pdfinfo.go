@@ -0,0 +1,113 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/example/shared/frontmatter"
+)
+
+var (
+	startXRefPattern = regexp.MustCompile(`startxref\s+(\d+)\s+%%EOF\s*$`)
+	trailerSize      = regexp.MustCompile(`/Size\s+(\d+)`)
+	trailerRoot      = regexp.MustCompile(`/Root\s+(\d+\s+\d+\s+R)`)
+	trailerID        = regexp.MustCompile(`/ID\s*\[[^\]]*\]`)
+	infoProducer     = regexp.MustCompile(`/Producer\s*(\((?:\\.|[^\\)])*\)|<[0-9A-Fa-f\s]*>)`)
+)
+
+// setPDFInfo adds the front matter metadata to the PDF document information
+// dictionary. Chrome only fills in the title, so a new dictionary is
+// appended as an incremental update, leaving the original bytes untouched.
+// The update keeps the file identifier and Chrome's producer.
+func setPDFInfo(pdf []byte, fm *FrontMatter) ([]byte, error) {
+	r, err := newPDFReader(pdf)
+	if err != nil {
+		return nil, err
+	}
+	sizeMatch := trailerSize.FindStringSubmatch(r.trailer)
+	rootMatch := trailerRoot.FindStringSubmatch(r.trailer)
+	if sizeMatch == nil || rootMatch == nil {
+		return nil, fmt.Errorf("incomplete trailer")
+	}
+	size, _ := strconv.Atoi(sizeMatch[1])
+
+	var producer string
+	if num, ok := ref(r.trailer, "Info"); ok {
+		if old, err := r.object(num); err == nil {
+			if m := infoProducer.FindStringSubmatch(old); m != nil {
+				producer = m[1]
+			}
+		}
+	}
+
+	var info strings.Builder
+	info.WriteString("<<")
+	addInfo := func(key, value string) {
+		if value != "" {
+			info.WriteString(fmt.Sprintf(" /%s %s", key, pdfString(value)))
+		}
+	}
+	addInfo("Title", fm.Title)
+	addInfo("Author", strings.Join(fm.Author, ", "))
+	addInfo("Subject", fm.Subject)
+	addInfo("Keywords", strings.Join(fm.Keywords, ", "))
+	addInfo("Creator", "markdown2pdf")
+	if producer != "" {
+		info.WriteString(" /Producer " + producer)
+	}
+	if date, ok := frontmatter.ParseDate(fm.Date); ok {
+		info.WriteString(" /CreationDate " + pdfDate(date))
+	} else {
+		info.WriteString(" /CreationDate " + pdfDate(time.Now()))
+	}
+	info.WriteString(" >>")
+
+	var buf bytes.Buffer
+	buf.Write(pdf)
+	if !bytes.HasSuffix(pdf, []byte("\n")) {
+		buf.WriteString("\n")
+	}
+
+	objOffset := buf.Len()
+	buf.WriteString(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", size, info.String()))
+
+	xrefOffset := buf.Len()
+	buf.WriteString(fmt.Sprintf("xref\n%d 1\n%010d 00000 n \n", size, objOffset))
+	id := trailerID.FindString(r.trailer)
+	if id != "" {
+		id = " " + id
+	}
+	buf.WriteString(fmt.Sprintf("trailer\n<< /Size %d /Root %s /Info %d 0 R%s /Prev %d >>\n",
+		size+1, rootMatch[1], size, id, r.xref))
+	buf.WriteString(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xrefOffset))
+
+	return buf.Bytes(), nil
+}
+
+// pdfString encodes text as a PDF hex string in UTF-16BE with a byte order
+// mark, which viewers accept for any script
+func pdfString(text string) string {
+	var buf strings.Builder
+	buf.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(text)) {
+		buf.WriteString(fmt.Sprintf("%04X", u))
+	}
+	buf.WriteString(">")
+	return buf.String()
+}
+
+// pdfDate formats a time as a PDF date string
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("(D:%s%s%02d'%02d')", t.Format("20060102150405"), sign, offset/3600, offset/60%60)
+}
@@ -0,0 +1,111 @@
+package converter
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/example/shared/frontmatter"
+)
+
+// chromePDF is a minimal PDF shaped like Chrome's output, with a document
+// information dictionary and a file identifier
+func chromePDF() []byte {
+	return buildPDF([]string{
+		"<</Type /Catalog\n/Pages 2 0 R>>",
+		"<</Type /Pages\n/Count 1\n/Kids [3 0 R]>>",
+		"<</Type /Page\n/Parent 2 0 R>>",
+		"<</Title (Draft)\n/Creator (Chromium)\n/Producer (Skia/PDF m126 \\(beta\\))\n/CreationDate (D:20240101000000+00'00')>>",
+	}, "<</Size 5\n/Root 1 0 R\n/Info 4 0 R\n/ID [<0123456789ABCDEF> <FEDCBA9876543210>]>>")
+}
+
+func TestSetPDFInfo(t *testing.T) {
+	original := chromePDF()
+	fm := &FrontMatter{Metadata: frontmatter.Metadata{
+		Title:    "Résumé",
+		Author:   frontmatter.StringList{"Ada", "Grace"},
+		Keywords: frontmatter.StringList{"go"},
+		Date:     "2024-03-05",
+	}}
+
+	pdf, err := setPDFInfo(original, fm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(pdf, original) {
+		t.Fatal("original bytes were changed")
+	}
+
+	r, err := newPDFReader(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, _ := newPDFReader(original)
+	for _, want := range []string{
+		"/Size 6",
+		"/Root 1 0 R",
+		"/Info 5 0 R",
+		"/ID [<0123456789ABCDEF> <FEDCBA9876543210>]",
+		"/Prev " + strconv.Itoa(previous.xref),
+	} {
+		if !strings.Contains(r.trailer, want) {
+			t.Errorf("trailer %q lacks %q", r.trailer, want)
+		}
+	}
+
+	info, err := r.object(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"/Title " + pdfString("Résumé"),
+		"/Author " + pdfString("Ada, Grace"),
+		"/Keywords " + pdfString("go"),
+		"/Creator " + pdfString("markdown2pdf"),
+		`/Producer (Skia/PDF m126 \(beta\))`,
+		"/CreationDate (D:20240305000000+00'00')",
+	} {
+		if !strings.Contains(info, want) {
+			t.Errorf("info %q lacks %q", info, want)
+		}
+	}
+	if strings.Contains(info, "/Subject") {
+		t.Errorf("info %q has an empty subject", info)
+	}
+
+	// Objects of the original file are still found through /Prev
+	if pages, err := r.pages(); err != nil || len(pages) != 1 {
+		t.Errorf("got pages %v, %v", pages, err)
+	}
+}
+
+func TestSetPDFInfoWithoutIdentifier(t *testing.T) {
+	original := buildPDF([]string{
+		"<</Type /Catalog\n/Pages 2 0 R>>",
+		"<</Type /Pages\n/Count 0\n/Kids []>>",
+	}, "<</Size 3\n/Root 1 0 R>>")
+
+	pdf, err := setPDFInfo(original, &FrontMatter{Metadata: frontmatter.Metadata{Title: "T"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trailer := regexp.MustCompile(`(?s)trailer\s*(<<[^>]*>>)\s*startxref\s+\d+\s+%%EOF\s*$`).FindSubmatch(pdf)
+	if trailer == nil {
+		t.Fatalf("no trailer at the end of %q", pdf)
+	}
+	if got := string(trailer[1]); strings.Contains(got, "/ID") || strings.Contains(got, "/Producer") {
+		t.Errorf("trailer %q has entries the original lacks", got)
+	}
+	if bytes.Contains(pdf[len(original):], []byte("/Producer")) {
+		t.Error("producer was made up")
+	}
+}
+
+func TestSetPDFInfoRejectsTruncatedFiles(t *testing.T) {
+	pdf := chromePDF()
+	if _, err := setPDFInfo(pdf[:len(pdf)-20], &FrontMatter{}); err == nil {
+		t.Error("no error")
+	}
+}
@@ -0,0 +1,211 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pdfReader reads objects from PDFs as Chrome prints them: a cross reference
+// table rather than a stream, and dictionaries outside object streams
+type pdfReader struct {
+	data    []byte
+	xref    int // offset of the last cross reference table
+	offsets map[int]int
+	trailer string // the last trailer dictionary
+}
+
+var (
+	pdfRefPattern   = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	pdfKidsPattern  = regexp.MustCompile(`/Kids\s*\[([^\]]*)\]`)
+	pdfDestPattern  = regexp.MustCompile(`/([^\s/\[\]<>()]+)\s*\[\s*(\d+)\s+\d+\s+R`)
+	pdfNameEscape   = regexp.MustCompile(`#([0-9A-Fa-f]{2})`)
+	pdfObjectHeader = regexp.MustCompile(`^\s*\d+\s+\d+\s+obj`)
+	trailerPrev     = regexp.MustCompile(`/Prev\s+(\d+)`)
+)
+
+// newPDFReader indexes the objects of the cross reference sections, from the
+// last one back through the /Prev entries of incremental updates
+func newPDFReader(data []byte) (*pdfReader, error) {
+	m := startXRefPattern.FindSubmatch(data)
+	if m == nil {
+		return nil, fmt.Errorf("no startxref found")
+	}
+	start, _ := strconv.Atoi(string(m[1]))
+
+	r := &pdfReader{data: data, xref: start, offsets: map[int]int{}}
+	for i := 0; ; i++ {
+		trailer, err := r.readXRef(start)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			r.trailer = trailer
+		}
+		prev := trailerPrev.FindStringSubmatch(trailer)
+		if prev == nil {
+			return r, nil
+		}
+		if i == 32 {
+			return nil, fmt.Errorf("too many cross reference sections")
+		}
+		start, _ = strconv.Atoi(prev[1])
+	}
+}
+
+// readXRef indexes the objects of the cross reference section at start that
+// aren't known yet, and returns the section's trailer
+func (r *pdfReader) readXRef(start int) (string, error) {
+	if start >= len(r.data) || !bytes.HasPrefix(r.data[start:], []byte("xref")) {
+		return "", fmt.Errorf("no cross reference table at offset %d", start)
+	}
+
+	section := r.data[start+len("xref"):]
+	end := bytes.Index(section, []byte("trailer"))
+	if end < 0 {
+		return "", fmt.Errorf("no trailer found")
+	}
+	trailerEnd := bytes.Index(section[end:], []byte("startxref"))
+	if trailerEnd < 0 {
+		return "", fmt.Errorf("incomplete trailer")
+	}
+
+	// Subsections are a first object number and a count, followed by an
+	// offset, generation and n or f per object
+	fields := strings.Fields(string(section[:end]))
+	for i := 0; i+1 < len(fields); {
+		first, err1 := strconv.Atoi(fields[i])
+		count, err2 := strconv.Atoi(fields[i+1])
+		if err1 != nil || err2 != nil || i+2+3*count > len(fields) {
+			return "", fmt.Errorf("malformed cross reference table")
+		}
+		for j := 0; j < count; j++ {
+			entry := fields[i+2+3*j:]
+			if _, known := r.offsets[first+j]; !known && entry[2] == "n" {
+				offset, _ := strconv.Atoi(entry[0])
+				r.offsets[first+j] = offset
+			}
+		}
+		i += 2 + 3*count
+	}
+	return string(section[end+len("trailer") : end+trailerEnd]), nil
+}
+
+// object returns the text of an object up to its stream data or end
+func (r *pdfReader) object(num int) (string, error) {
+	offset, ok := r.offsets[num]
+	if !ok || offset >= len(r.data) || !pdfObjectHeader.Match(r.data[offset:]) {
+		return "", fmt.Errorf("object %d not found", num)
+	}
+	obj := r.data[offset:]
+	end := bytes.Index(obj, []byte("endobj"))
+	if end < 0 {
+		return "", fmt.Errorf("object %d is not terminated", num)
+	}
+	obj = obj[:end]
+	if stream := bytes.Index(obj, []byte("stream")); stream >= 0 {
+		obj = obj[:stream]
+	}
+	return string(obj), nil
+}
+
+// ref returns the object number referenced by key in dict
+func ref(dict, key string) (int, bool) {
+	m := regexp.MustCompile(`/` + key + `\s+(\d+)\s+\d+\s+R`).FindStringSubmatch(dict)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	return n, err == nil
+}
+
+// pages returns the object numbers of the pages in document order
+func (r *pdfReader) pages() ([]int, error) {
+	root, ok := ref(r.trailer, "Root")
+	if !ok {
+		return nil, fmt.Errorf("no document catalog")
+	}
+	catalog, err := r.object(root)
+	if err != nil {
+		return nil, err
+	}
+	tree, ok := ref(catalog, "Pages")
+	if !ok {
+		return nil, fmt.Errorf("no page tree")
+	}
+
+	var pages []int
+	var walk func(num, depth int) error
+	walk = func(num, depth int) error {
+		if depth > 32 {
+			return fmt.Errorf("page tree too deep")
+		}
+		node, err := r.object(num)
+		if err != nil {
+			return err
+		}
+		kids := pdfKidsPattern.FindStringSubmatch(node)
+		if kids == nil {
+			pages = append(pages, num)
+			return nil
+		}
+		for _, m := range pdfRefPattern.FindAllStringSubmatch(kids[1], -1) {
+			kid, _ := strconv.Atoi(m[1])
+			if err := walk(kid, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(tree, 0); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// destinationPages returns the page number, counting from 1, of every named
+// destination. Chrome names destinations after the IDs of link targets.
+func (r *pdfReader) destinationPages() (map[string]int, error) {
+	pages, err := r.pages()
+	if err != nil {
+		return nil, err
+	}
+	pageNumbers := map[int]int{}
+	for i, num := range pages {
+		pageNumbers[num] = i + 1
+	}
+
+	root, _ := ref(r.trailer, "Root")
+	catalog, err := r.object(root)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]int{}
+	num, ok := ref(catalog, "Dests")
+	if !ok {
+		// Chrome only writes destinations that links point to
+		return result, nil
+	}
+	dests, err := r.object(num)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range pdfDestPattern.FindAllStringSubmatch(dests, -1) {
+		page, _ := strconv.Atoi(m[2])
+		if n, ok := pageNumbers[page]; ok {
+			result[pdfName(m[1])] = n
+		}
+	}
+	return result, nil
+}
+
+// pdfName decodes the #xx escapes of a PDF name
+func pdfName(name string) string {
+	return pdfNameEscape.ReplaceAllStringFunc(name, func(escape string) string {
+		b, _ := strconv.ParseUint(escape[1:], 16, 8)
+		return string([]byte{byte(b)})
+	})
+}
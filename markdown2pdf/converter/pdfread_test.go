@@ -0,0 +1,96 @@
+package converter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildPDF lays out objects, numbered from 1, with a cross reference table
+// and a trailer the way Chrome does
+func buildPDF(objects []string, trailer string) []byte {
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n%s\nstartxref\n%d\n%%%%EOF", trailer, xref)
+	return []byte(b.String())
+}
+
+// breakHeavyPDF has a nested page tree, as Chrome writes for longer
+// documents, and headings on pages 1, 3 and 4
+func breakHeavyPDF() []byte {
+	return buildPDF([]string{
+		"<</Type /Catalog\n/Pages 2 0 R\n/Dests 9 0 R>>",
+		"<</Type /Pages\n/Count 4\n/Kids [3 0 R 4 0 R]>>",
+		"<</Type /Pages\n/Parent 2 0 R\n/Count 2\n/Kids [5 0 R 6 0 R]>>",
+		"<</Type /Pages\n/Parent 2 0 R\n/Count 2\n/Kids [7 0 R 8 0 R]>>",
+		"<</Type /Page\n/Parent 3 0 R>>",
+		"<</Type /Page\n/Parent 3 0 R>>",
+		"<</Type /Page\n/Parent 4 0 R>>",
+		"<</Type /Page\n/Parent 4 0 R>>",
+		"<</introduction [5 0 R /XYZ 0 792 0]\n/caf#C3#A9-#23-1 [7 0 R /XYZ 0 400 0]\n/appendix [8 0 R /XYZ 0 792 0]>>",
+	}, "<</Size 10\n/Root 1 0 R>>")
+}
+
+func TestDestinationPages(t *testing.T) {
+	r, err := newPDFReader(breakHeavyPDF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := r.destinationPages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"introduction": 1, "café-#-1": 3, "appendix": 4}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("got %v, want %v", pages, want)
+	}
+}
+
+func TestDestinationPagesWithoutLinks(t *testing.T) {
+	pdf := buildPDF([]string{
+		"<</Type /Catalog\n/Pages 2 0 R>>",
+		"<</Type /Pages\n/Count 1\n/Kids [3 0 R]>>",
+		"<</Type /Page\n/Parent 2 0 R>>",
+	}, "<</Size 4\n/Root 1 0 R>>")
+
+	r, err := newPDFReader(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := r.destinationPages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 0 {
+		t.Errorf("got %v, want no destinations", pages)
+	}
+}
+
+func TestPDFReaderRejectsMalformedFiles(t *testing.T) {
+	valid := string(breakHeavyPDF())
+	for name, pdf := range map[string]string{
+		"no startxref":  strings.Replace(valid, "startxref", "", 1),
+		"bad offset":    valid[:strings.LastIndex(valid, "startxref")] + "startxref\n3\n%%EOF",
+		"no trailer":    strings.Replace(valid, "trailer", "", 1),
+		"missing pages": strings.Replace(valid, "/Kids [7 0 R 8 0 R]", "/Kids [7 0 R 99 0 R]", 1),
+	} {
+		r, err := newPDFReader([]byte(pdf))
+		if err == nil {
+			_, err = r.destinationPages()
+		}
+		if err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// DefaultTOCDepth is the deepest heading level listed when Options.TOCDepth
+// is not set
+const DefaultTOCDepth = 3
+
+// tocMarker is a paragraph marking where the table of contents is placed.
+// Without it the table of contents goes at the start of the document.
+const tocMarker = "[TOC]"
+
+// KindTOC is the node kind of a table of contents
+var KindTOC = ast.NewNodeKind("TOC")
+
+// tocEntry is a heading listed in the table of contents
+type tocEntry struct {
+	Level int
+	ID    string
+	Title string
+}
+
+// tocNode is a block holding the table of contents
+type tocNode struct {
+	ast.BaseBlock
+	Entries []tocEntry
+}
+
+// Kind implements ast.Node
+func (n *tocNode) Kind() ast.NodeKind {
+	return KindTOC
+}
+
+// Dump implements ast.Node
+func (n *tocNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// tocExtension adds a table of contents of the headings up to Depth
+type tocExtension struct {
+	Depth int
+}
+
+// Extend implements goldmark.Extender
+func (e *tocExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&tocTransformer{depth: e.Depth}, 1000),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&tocRenderer{}, 500),
+	))
+}
+
+// tocTransformer collects headings and inserts the table of contents at the
+// marker or at the start of the document
+type tocTransformer struct {
+	depth int
+}
+
+// Transform implements parser.ASTTransformer
+func (t *tocTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	toc := &tocNode{}
+	var marker ast.Node
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading:
+			if n.Level > t.depth {
+				return ast.WalkSkipChildren, nil
+			}
+			id, ok := n.AttributeString("id")
+			if !ok {
+				return ast.WalkSkipChildren, nil
+			}
+			idBytes, _ := id.([]byte)
+			toc.Entries = append(toc.Entries, tocEntry{
+				Level: n.Level,
+				ID:    string(idBytes),
+				Title: strings.TrimSpace(nodeText(n, source)),
+			})
+			return ast.WalkSkipChildren, nil
+		case *ast.Paragraph:
+			if marker == nil && strings.EqualFold(strings.TrimSpace(nodeText(n, source)), tocMarker) {
+				marker = n
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	if marker != nil {
+		marker.Parent().ReplaceChild(marker.Parent(), marker, toc)
+	} else if first := doc.FirstChild(); first != nil {
+		doc.InsertBefore(doc, first, toc)
+	} else {
+		doc.AppendChild(doc, toc)
+	}
+}
+
+// nodeText returns the plain text content of a node
+func nodeText(node ast.Node, source []byte) string {
+	var buf strings.Builder
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch child := child.(type) {
+		case *ast.Text:
+			buf.Write(child.Segment.Value(source))
+			if child.SoftLineBreak() {
+				buf.WriteString(" ")
+			}
+		case *ast.String:
+			buf.Write(child.Value)
+		default:
+			buf.WriteString(nodeText(child, source))
+		}
+	}
+	return buf.String()
+}
+
+// tocRenderer renders the table of contents as nested lists. Page numbers
+// are filled in by the browser once the document is laid out.
+type tocRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *tocRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindTOC, r.renderTOC)
+}
+
+func (r *tocRenderer) renderTOC(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*tocNode)
+	if len(n.Entries) == 0 {
+		return ast.WalkContinue, nil
+	}
+
+	// Nest relative to the shallowest heading so a document without an h1
+	// doesn't start at the second level
+	base := n.Entries[0].Level
+	for _, e := range n.Entries {
+		if e.Level < base {
+			base = e.Level
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<nav class=\"toc\">\n<ul>\n")
+	depth := 0
+	for i, e := range n.Entries {
+		level := e.Level - base
+		switch {
+		case i == 0:
+			for ; depth < level; depth++ {
+				buf.WriteString("<li>\n<ul>\n")
+			}
+		case level > depth:
+			// Skipped levels get an empty item to nest in
+			buf.WriteString("\n<ul>\n")
+			for depth++; depth < level; depth++ {
+				buf.WriteString("<li>\n<ul>\n")
+			}
+		default:
+			buf.WriteString("</li>\n")
+			for ; depth > level; depth-- {
+				buf.WriteString("</ul>\n</li>\n")
+			}
+		}
+		buf.WriteString(fmt.Sprintf(`<li><a href="#%s"><span class="toc-title">%s</span><span class="toc-page"></span></a>`,
+			html.EscapeString(e.ID), html.EscapeString(e.Title)))
+	}
+	buf.WriteString("</li>\n")
+	for ; depth > 0; depth-- {
+		buf.WriteString("</ul>\n</li>\n")
+	}
+	buf.WriteString("</ul>\n</nav>\n")
+
+	_, _ = w.Write(buf.Bytes())
+	return ast.WalkContinue, nil
+}
+
+// tocPagesScript fills in an estimated page number for every table of
+// contents entry from the position of its heading. It runs with print media emulated and
+// the viewport set to the printable width, and is passed the printable page
+// height in CSS pixels.
+const tocPagesScript = `((pageHeight) => {
+	for (const a of document.querySelectorAll('.toc a[href^="#"]')) {
+		const target = document.getElementById(decodeURIComponent(a.hash.slice(1)));
+		const page = a.querySelector('.toc-page');
+		if (!target || !page) continue;
+		const top = target.getBoundingClientRect().top + window.scrollY;
+		page.textContent = String(Math.floor(top / pageHeight) + 1);
+	}
+	return true;
+})(%f)`
+
+// tocSetPagesScript sets the page numbers of table of contents entries from
+// a map of heading IDs to pages, and reports whether any number changed
+const tocSetPagesScript = `((pages) => {
+	let changed = false;
+	for (const a of document.querySelectorAll('.toc a[href^="#"]')) {
+		const n = pages[decodeURIComponent(a.hash.slice(1))];
+		const page = a.querySelector('.toc-page');
+		if (!n || !page || page.textContent === String(n)) continue;
+		page.textContent = String(n);
+		changed = true;
+	}
+	return changed;
+})(%s)`
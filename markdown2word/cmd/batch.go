@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/example/markdown2word/converter"
+	"github.com/example/shared/batch"
+)
+
+// runBatch converts jobs in parallel, then prints a summary. It returns an
+// error if any file failed, after trying all of them. Every job gets its own
+// Converter, as a Converter holds the state of the document being converted.
+func runBatch(jobs []batch.Job, opts converter.Options, deps []string) error {
+	return batch.Run(jobs, jobCount, force, deps, func(int) (batch.ConvertFunc, func(), error) {
+		convert := func(j batch.Job) ([]string, error) {
+			c := converter.New(opts)
+			err := c.ConvertFile(j.Input, j.Output)
+			return c.Warnings(), err
+		}
+		return convert, func() {}, nil
+	})
+}
@@ -2,11 +2,15 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/example/markdown2word/converter"
+	"github.com/example/shared/batch"
 	"github.com/spf13/cobra"
 )
 
@@ -20,6 +24,14 @@ var (
 	codeFontFamily string
 	codeFontSize   float64
 
+	// Fonts for non-Latin scripts
+	eastAsianFont     string
+	complexScriptFont string
+
+	// Code block settings
+	codeStyle   string
+	lineNumbers bool
+
 	// Page margins in inches
 	marginTop    float64
 	marginBottom float64
@@ -29,15 +41,42 @@ var (
 	// Page size
 	pageSize string
 
+	// Reference document used as a template
+	referenceDocx string
+
+	// Start with a title page built from the front matter
+	titlePage bool
+
+	// Place footnotes at the end of the document
+	endnotes bool
+
+	// Fenced code blocks rendered to images
+	fenceRendererSpecs []string
+	fenceCacheDir      string
+
+	// Batch conversion
+	outDir    string
+	recursive bool
+	jobCount  int
+	force     bool
+
 	// Convert command
 	convertCmd = &cobra.Command{
-		Use:   "convert <input.md>",
+		Use:   "convert <input.md|dir|glob>...",
 		Short: "Convert a Markdown file to Word document",
 		Long: `Convert a Markdown file to Word (.docx) format.
 
-The convert command takes a Markdown file as input and generates a Word document.
-By default, the output file will have the same name as the input file but with
-a .docx extension.
+The convert command takes one or more Markdown files as input and generates a
+Word document for each. By default, the output file will have the same name as
+the input file but with a .docx extension.
+
+Inputs may also be directories (searched recursively with --recursive) or glob
+patterns, where "**" matches any number of directories. When several files are
+converted, --jobs files are converted in parallel, files whose document is
+newer than the Markdown source and the --reference-docx template are skipped
+unless --force is given, and a summary is printed at the end. Changes to other
+options aren't noticed, so give --force after changing them. The command
+exits with an error if any file failed.
 
 Supported page sizes:
   - Letter (default): 8.5in x 11in
@@ -51,6 +90,15 @@ Examples:
   # Specify output file
   markdown2word convert README.md -o documentation.docx
 
+  # Read Markdown from standard input and write the document to standard output
+  cat README.md | markdown2word convert - > README.docx
+
+  # Convert a directory tree into build/docx with 4 parallel workers
+  markdown2word convert --recursive docs/ --out-dir build/docx --jobs 4
+
+  # Convert all Markdown files below docs matching a pattern
+  markdown2word convert "docs/**/*.md"
+
   # Use custom font settings
   markdown2word convert README.md --font-family "Arial" --font-size 11
 
@@ -58,8 +106,23 @@ Examples:
   markdown2word convert README.md --page-size A4 --margin-top 1 --margin-bottom 1
 
   # Customize code block font
-  markdown2word convert README.md --code-font-family "Consolas" --code-font-size 9`,
-		Args: cobra.ExactArgs(1),
+  markdown2word convert README.md --code-font-family "Consolas" --code-font-size 9
+
+  # Set fonts for Chinese and Arabic text
+  markdown2word convert README.md --east-asian-font "Microsoft YaHei" --complex-script-font "Arial"
+
+  # Highlight code with the monokai style and show line numbers
+  markdown2word convert README.md --code-style monokai --line-numbers
+
+  # Use the styles, headers and footers of a corporate template
+  markdown2word convert README.md --reference-docx template.docx
+
+  # Draw Graphviz and PlantUML blocks as images with the tools' default commands
+  markdown2word convert README.md --fence-renderer dot --fence-renderer plantuml
+
+  # Draw Graphviz blocks with a custom command
+  markdown2word convert README.md --fence-renderer dot='dot -Tpng -Gdpi=150'`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: runConvert,
 	}
 )
@@ -75,6 +138,12 @@ func init() {
 	convertCmd.Flags().Float64Var(&fontSize, "font-size", 11, "Font size in points for body text")
 	convertCmd.Flags().StringVar(&codeFontFamily, "code-font-family", "Consolas", "Font family for code blocks")
 	convertCmd.Flags().Float64Var(&codeFontSize, "code-font-size", 10, "Font size in points for code blocks")
+	convertCmd.Flags().StringVar(&eastAsianFont, "east-asian-font", "", "Font family for East Asian text such as Chinese, Japanese and Korean")
+	convertCmd.Flags().StringVar(&complexScriptFont, "complex-script-font", "", "Font family for complex script text such as Arabic and Hebrew")
+
+	// Code block flags
+	convertCmd.Flags().StringVar(&codeStyle, "code-style", converter.DefaultCodeStyle, "Syntax highlighting style for code blocks (e.g. github, monokai, dracula)")
+	convertCmd.Flags().BoolVar(&lineNumbers, "line-numbers", false, "Show line numbers in code blocks")
 
 	// Margin flags (in inches)
 	convertCmd.Flags().Float64Var(&marginTop, "margin-top", 1.0, "Top margin in inches")
@@ -84,51 +153,190 @@ func init() {
 
 	// Page size flag
 	convertCmd.Flags().StringVar(&pageSize, "page-size", "Letter", "Page size: Letter, A4, Legal")
+
+	// Reference document flag
+	convertCmd.Flags().StringVar(&referenceDocx, "reference-docx", "", "Word document whose styles, headers, footers and page setup are used for the output")
+
+	// Front matter flags
+	convertCmd.Flags().BoolVar(&titlePage, "title-page", false, "Start the document with a title page built from the front matter title, subject, authors and date")
+
+	// Footnote flags
+	convertCmd.Flags().BoolVar(&endnotes, "endnotes", false, "Place footnotes at the end of the document as endnotes instead of at the bottom of each page")
+
+	// Fence renderer flags
+	convertCmd.Flags().StringArrayVar(&fenceRendererSpecs, "fence-renderer", nil, "Render fenced code blocks of a language to images with a command reading the block on stdin and writing PNG to stdout, as lang='command' or just lang for dot, mermaid and plantuml (repeatable)")
+	convertCmd.Flags().StringVar(&fenceCacheDir, "fence-cache-dir", "", "Directory caching rendered fenced code blocks (default: markdown2word/fences in the user cache directory)")
+
+	// Batch flags
+	convertCmd.Flags().StringVar(&outDir, "out-dir", "", "Write documents to this directory, mirroring the input directory tree")
+	convertCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Convert Markdown files in subdirectories of directory inputs")
+	convertCmd.Flags().IntVarP(&jobCount, "jobs", "j", runtime.NumCPU(), "Number of files to convert in parallel")
+	convertCmd.Flags().BoolVar(&force, "force", false, "Convert files even if their document is up to date")
 }
 
 func runConvert(cmd *cobra.Command, args []string) error {
-	inputFile := args[0]
+	// Create converter options
+	opts := converter.Options{
+		FontFamily:        fontFamily,
+		FontSize:          fontSize,
+		CodeFontFamily:    codeFontFamily,
+		CodeFontSize:      codeFontSize,
+		EastAsianFont:     eastAsianFont,
+		ComplexScriptFont: complexScriptFont,
+		CodeStyle:         codeStyle,
+		LineNumbers:       lineNumbers,
+		MarginTop:         marginTop,
+		MarginBottom:      marginBottom,
+		MarginLeft:        marginLeft,
+		MarginRight:       marginRight,
+		PageSize:          pageSize,
+		ReferenceDocx:     referenceDocx,
+		TitlePage:         titlePage,
+		Endnotes:          endnotes,
+		FenceCacheDir:     fenceCacheDir,
+	}
+
+	renderers, err := parseFenceRenderers(fenceRendererSpecs)
+	if err != nil {
+		return err
+	}
+	opts.FenceRenderers = renderers
+	if len(renderers) > 0 && opts.FenceCacheDir == "" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			opts.FenceCacheDir = filepath.Join(dir, "markdown2word", "fences")
+		}
+	}
+
+	// "-" reads Markdown from standard input and, without --output, writes
+	// the document to standard output
+	if slices.Contains(args, stdio) {
+		if len(args) > 1 {
+			return fmt.Errorf("standard input (-) cannot be combined with other inputs")
+		}
+		output := outputFile
+		if output == "" {
+			output = stdio
+		}
+		return convertFile(converter.New(opts), stdio, output)
+	}
+
+	jobs, err := batch.Collect(args, ".docx", outDir, recursive)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		return fmt.Errorf("no Markdown files found")
+	}
+
+	// A single file given by name is always converted, without a summary
+	if len(args) == 1 && len(jobs) == 1 && !batch.IsGlob(args[0]) && jobs[0].Input == args[0] {
+		return convertSingle(jobs[0], opts)
+	}
+
+	if outputFile != "" {
+		return fmt.Errorf("--output can only be used with a single input file; use --out-dir instead")
+	}
 
-	// Validate input file exists
-	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
-		return fmt.Errorf("input file does not exist: %s", inputFile)
+	// Conversions are redone when the reference document changes
+	var deps []string
+	if referenceDocx != "" {
+		deps = append(deps, referenceDocx)
 	}
 
+	return runBatch(jobs, opts, deps)
+}
+
+// parseFenceRenderers parses --fence-renderer values of the form
+// lang=command, or lang alone for a tool in converter.DefaultFenceCommands
+func parseFenceRenderers(specs []string) (map[string]converter.FenceRenderer, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	renderers := map[string]converter.FenceRenderer{}
+	for _, spec := range specs {
+		language, command, found := strings.Cut(spec, "=")
+		language = strings.ToLower(strings.TrimSpace(language))
+		if language == "" {
+			return nil, fmt.Errorf("invalid --fence-renderer %q: missing language", spec)
+		}
+		if !found {
+			var ok bool
+			if command, ok = converter.DefaultFenceCommands[language]; !ok {
+				return nil, fmt.Errorf("no default command for %s blocks; use --fence-renderer %s='command'", language, language)
+			}
+		}
+		if strings.TrimSpace(command) == "" {
+			return nil, fmt.Errorf("invalid --fence-renderer %q: missing command", spec)
+		}
+		renderers[language] = converter.CommandRenderer(command)
+	}
+	return renderers, nil
+}
+
+// convertSingle converts one file named on the command line
+func convertSingle(j batch.Job, opts converter.Options) error {
+	inputFile := j.Input
+
 	// Check if input file is a Markdown file
-	ext := strings.ToLower(filepath.Ext(inputFile))
-	if ext != ".md" && ext != ".markdown" {
+	if !batch.IsMarkdown(inputFile) {
 		fmt.Fprintf(os.Stderr, "Warning: input file does not have .md or .markdown extension\n")
 	}
 
 	// Determine output file path
-	output := outputFile
-	if output == "" {
-		// Replace extension with .docx
-		baseName := strings.TrimSuffix(inputFile, filepath.Ext(inputFile))
-		output = baseName + ".docx"
+	output := j.Output
+	if outputFile != "" {
+		output = outputFile
+	} else if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Create converter options
-	opts := converter.Options{
-		FontFamily:     fontFamily,
-		FontSize:       fontSize,
-		CodeFontFamily: codeFontFamily,
-		CodeFontSize:   codeFontSize,
-		MarginTop:      marginTop,
-		MarginBottom:   marginBottom,
-		MarginLeft:     marginLeft,
-		MarginRight:    marginRight,
-		PageSize:       pageSize,
-	}
-
-	// Convert the file
-	fmt.Printf("Converting %s to %s...\n", inputFile, output)
-
-	c := converter.New(opts)
-	if err := c.ConvertFile(inputFile, output); err != nil {
+	return convertFile(converter.New(opts), inputFile, output)
+}
+
+// stdio is the file name standing for standard input or output
+const stdio = "-"
+
+// convertFile converts one file and prints its progress and warnings. An
+// input or output of "-" means standard input or output, in which case
+// progress goes to standard error.
+func convertFile(c *converter.Converter, inputFile, output string) error {
+	status := os.Stdout
+	if output == stdio {
+		status = os.Stderr
+	}
+	fmt.Fprintf(status, "Converting %s to %s...\n", displayName(inputFile, "stdin"), displayName(output, "stdout"))
+
+	var err error
+	switch {
+	case inputFile == stdio && output == stdio:
+		err = c.ConvertReader(os.Stdin, os.Stdout)
+	case inputFile == stdio:
+		var content []byte
+		if content, err = io.ReadAll(os.Stdin); err == nil {
+			err = c.Convert(content, output)
+		}
+	case output == stdio:
+		err = c.ConvertFileTo(inputFile, os.Stdout)
+	default:
+		err = c.ConvertFile(inputFile, output)
+	}
+	if err != nil {
 		return fmt.Errorf("conversion failed: %w", err)
 	}
 
-	fmt.Printf("Successfully converted to %s\n", output)
+	for _, warning := range c.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	fmt.Fprintf(status, "Successfully converted to %s\n", displayName(output, "stdout"))
 	return nil
 }
+
+// displayName names a file in progress messages, using std for "-"
+func displayName(path, std string) string {
+	if path == stdio {
+		return std
+	}
+	return path
+}
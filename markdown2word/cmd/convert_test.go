@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/example/markdown2word/converter"
+)
+
+// execute runs the command line args with stdin as standard input and
+// returns what was written to standard output and standard error
+func execute(t *testing.T, stdin string, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	var files [3]*os.File
+	for i, name := range []string{"stdin", "stdout", "stderr"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files[i] = f
+	}
+	if _, err := io.WriteString(files[0], stdin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files[0].Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := [3]*os.File{os.Stdin, os.Stdout, os.Stderr}
+	os.Stdin, os.Stdout, os.Stderr = files[0], files[1], files[2]
+	defer func() { os.Stdin, os.Stdout, os.Stderr = saved[0], saved[1], saved[2] }()
+
+	// Flags keep their values between executions
+	outputFile = ""
+
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+
+	out, readErr := os.ReadFile(files[1].Name())
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	errOut, readErr := os.ReadFile(files[2].Name())
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	return string(out), string(errOut), err
+}
+
+// documentXML returns the main document part of a Word document
+func documentXML(t *testing.T, docx []byte) string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(docx), int64(len(docx)))
+	if err != nil {
+		t.Fatalf("not a Word document: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rc.Close()
+			data, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(data)
+		}
+	}
+	t.Fatal("no word/document.xml")
+	return ""
+}
+
+func TestConvertStdinToStdout(t *testing.T) {
+	stdout, stderr, err := execute(t, "# Piped\n", "convert", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(documentXML(t, []byte(stdout)), "Piped") {
+		t.Error("heading missing from the document")
+	}
+	if !strings.Contains(stderr, "Converting stdin to stdout...") || !strings.Contains(stderr, "Successfully converted to stdout") {
+		t.Errorf("progress not on standard error: %q", stderr)
+	}
+}
+
+func TestConvertFileToStdout(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "doc.md")
+	if err := os.WriteFile(input, []byte("# Stored\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := execute(t, "", "convert", input, "-o", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(documentXML(t, []byte(stdout)), "Stored") {
+		t.Error("heading missing from the document")
+	}
+	if !strings.Contains(stderr, "Converting "+input+" to stdout...") {
+		t.Errorf("progress not on standard error: %q", stderr)
+	}
+}
+
+func TestConvertStdinToFile(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "out.docx")
+
+	stdout, stderr, err := execute(t, "# Saved\n", "convert", "-", "-o", output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docx, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(documentXML(t, docx), "Saved") {
+		t.Error("heading missing from the document")
+	}
+	if !strings.Contains(stdout, "Successfully converted to "+output) || stderr != "" {
+		t.Errorf("got standard output %q and error %q", stdout, stderr)
+	}
+}
+
+func TestConvertStdinWithOtherInputs(t *testing.T) {
+	stdout, _, err := execute(t, "# Text\n", "convert", "-", "other.md")
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Errorf("got error %v", err)
+	}
+	if stdout != "" {
+		t.Errorf("wrote %q", stdout)
+	}
+}
+
+func TestParseFenceRenderers(t *testing.T) {
+	tests := []struct {
+		specs   []string
+		want    map[string]converter.FenceRenderer
+		wantErr string
+	}{
+		{nil, nil, ""},
+		{[]string{"dot"}, map[string]converter.FenceRenderer{"dot": converter.CommandRenderer("dot -Tpng")}, ""},
+		{[]string{"Mermaid", "plantuml"}, map[string]converter.FenceRenderer{
+			"mermaid":  converter.CommandRenderer(converter.DefaultFenceCommands["mermaid"]),
+			"plantuml": converter.CommandRenderer(converter.DefaultFenceCommands["plantuml"]),
+		}, ""},
+		{[]string{"dot=dot -Tpng -Gdpi=150"}, map[string]converter.FenceRenderer{"dot": converter.CommandRenderer("dot -Tpng -Gdpi=150")}, ""},
+		{[]string{"svgbob=svgbob --png"}, map[string]converter.FenceRenderer{"svgbob": converter.CommandRenderer("svgbob --png")}, ""},
+		{[]string{"dot", "dot=my-dot"}, map[string]converter.FenceRenderer{"dot": converter.CommandRenderer("my-dot")}, ""},
+		{[]string{"=dot -Tpng"}, nil, "missing language"},
+		{[]string{" =dot"}, nil, "missing language"},
+		{[]string{"dot="}, nil, "missing command"},
+		{[]string{"dot=  "}, nil, "missing command"},
+		{[]string{"svgbob"}, nil, "no default command for svgbob blocks"},
+	}
+	for _, tt := range tests {
+		got, err := parseFenceRenderers(tt.specs)
+		switch {
+		case tt.wantErr != "":
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%q: got error %v, want %q", tt.specs, err, tt.wantErr)
+			}
+		case err != nil:
+			t.Errorf("%q: %v", tt.specs, err)
+		case !reflect.DeepEqual(got, tt.want):
+			t.Errorf("%q: got %v, want %v", tt.specs, got, tt.want)
+		}
+	}
+}
@@ -5,15 +5,21 @@ import (
 	"bytes"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode"
 
+	"github.com/example/shared/texmath"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
 // Options contains the configuration for Word document generation
@@ -24,6 +30,11 @@ type Options struct {
 	CodeFontFamily string
 	CodeFontSize   float64
 
+	// Fonts for East Asian (e.g. Chinese) and complex script (e.g. Arabic)
+	// text; Word's defaults are used when empty
+	EastAsianFont     string
+	ComplexScriptFont string
+
 	// Page margins in inches
 	MarginTop    float64
 	MarginBottom float64
@@ -32,14 +43,98 @@ type Options struct {
 
 	// Page size: Letter, A4, Legal
 	PageSize string
+
+	// Syntax highlighting style for code blocks (chroma style name)
+	CodeStyle string
+
+	// Show line numbers in code blocks
+	LineNumbers bool
+
+	// Path to a .docx whose styles, numbering, settings, theme, headers,
+	// footers and section properties are reused for the output
+	ReferenceDocx string
+
+	// Start the document with a title page built from the front matter
+	TitlePage bool
+
+	// Make Markdown footnotes endnotes, placed at the end of the document
+	Endnotes bool
+
+	// Renderers turning fenced code blocks into images, keyed by fence
+	// language (matched case-insensitively). Other blocks stay code.
+	FenceRenderers map[string]FenceRenderer
+
+	// Directory where images made by command renderers are cached by
+	// content hash across runs; they are only cached in memory when empty
+	FenceCacheDir string
 }
 
-// Converter handles Markdown to Word conversion
+// Converter handles Markdown to Word conversion. It holds the state of the
+// document being converted, so it must not be used by several goroutines at
+// once; create a Converter per goroutine instead.
 type Converter struct {
 	opts       Options
 	paragraphs []string
+
+	// Directory used to resolve relative image paths
+	baseDir string
+
+	// Template loaded from Options.ReferenceDocx
+	reference *referenceDocx
+
+	// Front matter of the document being converted, if any
+	meta *FrontMatter
+
+	// Package parts collected while processing the document
+	relationships []relationship
+	media         []mediaFile
+	mediaIndex    map[string]int
+	hyperlinks    map[string]string
+	numbering     []numberingInstance
+	drawings      int
+	bookmarks     int
+	bookmarkNames map[string]string
+
+	// Footnote definitions by number, and the Word notes made from them
+	// with their own relationships, hyperlinks and images. Each footnote
+	// becomes one note, whose reference mark is bookmarked so that further
+	// citations can refer to it.
+	footnotes      map[int]*east.Footnote
+	notes          []string
+	noteIDs        map[int]int
+	noteNumbers    map[int]int
+	noteBookmarks  map[int]string
+	noteRels       []relationship
+	noteHyperlinks map[string]string
+	noteMedia      map[string]int
+	noteIDBase     int
+	inNote         bool
+
+	// Images of rendered fenced blocks by content hash, kept across
+	// conversions
+	fenceImages map[string][]byte
+
+	// Non-fatal problems encountered during conversion
+	warnings           []string
+	unknownStyleWarned bool
+}
+
+// relationship represents an entry in word/_rels/document.xml.rels
+type relationship struct {
+	ID         string
+	Type       string
+	Target     string
+	TargetMode string
 }
 
+// Relationship types used by the document part
+const (
+	relTypeStyles    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+	relTypeImage     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+	relTypeHyperlink = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+	relTypeNumbering = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering"
+)
+
 // New creates a new Converter with the given options
 func New(opts Options) *Converter {
 	return &Converter{opts: opts, paragraphs: []string{}}
@@ -47,82 +142,207 @@ func New(opts Options) *Converter {
 
 // ConvertFile reads a Markdown file and converts it to Word document
 func (c *Converter) ConvertFile(inputPath, outputPath string) error {
+	var buf bytes.Buffer
+	if err := c.ConvertFileTo(inputPath, &buf); err != nil {
+		return err
+	}
+	return writeDocx(outputPath, buf.Bytes())
+}
+
+// ConvertFileTo reads a Markdown file and writes the Word document to w
+func (c *Converter) ConvertFileTo(inputPath string, w io.Writer) error {
 	content, err := os.ReadFile(inputPath)
 	if err != nil {
 		return fmt.Errorf("failed to read input file: %w", err)
 	}
 
-	return c.Convert(content, outputPath)
+	return c.convert(content, filepath.Dir(inputPath), w)
+}
+
+// ConvertReader reads Markdown from r and writes the Word document to w.
+// Relative image paths resolve against the current directory.
+func (c *Converter) ConvertReader(r io.Reader, w io.Writer) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	return c.ConvertTo(content, w)
+}
+
+// Warnings returns the non-fatal problems found by the last conversion
+func (c *Converter) Warnings() []string {
+	return c.warnings
+}
+
+// warn records a non-fatal conversion problem
+func (c *Converter) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// relIDBase returns the offset for relationship IDs so they don't clash
+// with those of a reference document
+func (c *Converter) relIDBase() int {
+	if c.reference == nil {
+		return 0
+	}
+	return c.reference.maxRelID
+}
+
+// addRelationship registers a document relationship and returns its ID
+func (c *Converter) addRelationship(relType, target string, external bool) string {
+	id := fmt.Sprintf("rId%d", c.relIDBase()+len(c.relationships)+1)
+	rel := relationship{ID: id, Type: relType, Target: target}
+	if external {
+		rel.TargetMode = "External"
+	}
+	c.relationships = append(c.relationships, rel)
+	return id
 }
 
 // Convert converts Markdown content to Word document
 func (c *Converter) Convert(markdown []byte, outputPath string) error {
+	var buf bytes.Buffer
+	if err := c.ConvertTo(markdown, &buf); err != nil {
+		return err
+	}
+	return writeDocx(outputPath, buf.Bytes())
+}
+
+// writeDocx writes a generated document to a file
+func writeDocx(outputPath string, docx []byte) error {
+	if err := os.WriteFile(outputPath, docx, 0644); err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
+	}
+	return nil
+}
+
+// ConvertTo converts Markdown content to Word document and writes it to w.
+// Nothing is written if the conversion fails. Relative image paths resolve
+// against the current directory.
+func (c *Converter) ConvertTo(markdown []byte, w io.Writer) error {
+	return c.convert(markdown, "", w)
+}
+
+// convert converts Markdown content whose relative references resolve
+// against baseDir, or the current directory when it is empty
+func (c *Converter) convert(markdown []byte, baseDir string, w io.Writer) error {
+	c.baseDir = baseDir
+
+	// Front matter overrides apply to this document only
+	defer func(opts Options) { c.opts = opts }(c.opts)
+
+	meta, body, err := parseFrontMatter(markdown)
+	if err != nil {
+		return err
+	}
+	c.meta = meta
+	if meta != nil {
+		c.applyFrontMatter(meta)
+	}
+
+	// Load the reference document first so its IDs can be avoided
+	c.reference = nil
+	if c.opts.ReferenceDocx != "" {
+		ref, err := loadReferenceDocx(c.opts.ReferenceDocx)
+		if err != nil {
+			return fmt.Errorf("failed to load reference document: %w", err)
+		}
+		c.reference = ref
+	}
+
 	// Parse Markdown
-	md := goldmark.New(
+	reader := text.NewReader(body)
+	root := newMarkdown().Parser().Parse(reader)
+
+	// Convert AST to paragraphs
+	c.paragraphs = []string{}
+	c.relationships = nil
+	c.media = nil
+	c.mediaIndex = map[string]int{}
+	c.hyperlinks = map[string]string{}
+	c.numbering = nil
+	c.drawings = 0
+	c.bookmarks = 0
+	c.warnings = nil
+	c.unknownStyleWarned = false
+	c.notes = nil
+	c.noteRels = nil
+	c.collectBookmarks(root)
+	c.collectFootnotes(root)
+	if c.opts.TitlePage && c.meta != nil {
+		c.addTitlePage()
+	}
+	c.processNode(root, body)
+
+	// Create docx package
+	docx, err := c.createDocx()
+	if err != nil {
+		return fmt.Errorf("failed to create document: %w", err)
+	}
+
+	if _, err := w.Write(docx); err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
+	}
+
+	return nil
+}
+
+// newMarkdown creates the goldmark instance used to parse input documents
+func newMarkdown() goldmark.Markdown {
+	return goldmark.New(
 		goldmark.WithExtensions(
 			extension.GFM,
 			extension.Table,
 			extension.Strikethrough,
 			extension.TaskList,
+			extension.Footnote,
+			texmath.Extension,
 		),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
 		),
 	)
-
-	reader := text.NewReader(markdown)
-	root := md.Parser().Parse(reader)
-
-	// Convert AST to paragraphs
-	c.paragraphs = []string{}
-	c.processNode(root, markdown)
-
-	// Create docx file
-	if err := c.createDocx(outputPath); err != nil {
-		return fmt.Errorf("failed to create document: %w", err)
-	}
-
-	return nil
 }
 
 // processNode recursively processes AST nodes
 func (c *Converter) processNode(node ast.Node, source []byte) {
 	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
-		switch n := child.(type) {
-		case *ast.Heading:
-			c.addHeading(n, source)
-		case *ast.Paragraph:
-			c.addParagraph(n, source)
-		case *ast.FencedCodeBlock:
-			c.addCodeBlock(n, source)
-		case *ast.CodeBlock:
-			c.addCodeBlock(n, source)
-		case *ast.List:
-			c.addList(n, source, 0)
-		case *ast.Blockquote:
-			c.addBlockquote(n, source)
-		case *ast.ThematicBreak:
-			c.addHorizontalRule()
-		case *ast.HTMLBlock:
-			// Skip HTML blocks
-		default:
-			// Recursively process other nodes
-			c.processNode(child, source)
-		}
+		c.processBlock(child, source)
 	}
 }
 
-// Font sizes in half-points for headings
-var headingSizes = map[int]int{
-	1: 48, // 24pt
-	2: 40, // 20pt
-	3: 32, // 16pt
-	4: 28, // 14pt
-	5: 24, // 12pt
-	6: 22, // 11pt
+// processBlock processes a single block-level node
+func (c *Converter) processBlock(node ast.Node, source []byte) {
+	switch n := node.(type) {
+	case *ast.Heading:
+		c.addHeading(n, source)
+	case *ast.Paragraph:
+		c.addParagraph(n, source)
+	case *ast.FencedCodeBlock:
+		c.addCodeBlock(n, source)
+	case *ast.CodeBlock:
+		c.addCodeBlock(n, source)
+	case *ast.List:
+		c.addList(n, source, 0)
+	case *ast.Blockquote:
+		c.addBlockquote(n, source)
+	case *ast.ThematicBreak:
+		c.addHorizontalRule()
+	case *east.Table:
+		c.addTable(n, source)
+	case *texmath.Block:
+		c.addMathBlock(n, source)
+	case *east.FootnoteList:
+		// Notes are written where they are referenced
+	case *ast.HTMLBlock:
+		// Skip HTML blocks
+	default:
+		// Recursively process other nodes
+		c.processNode(node, source)
+	}
 }
 
-// addHeading adds a heading to the document
+// addHeading adds a heading to the document using the HeadingN style
 func (c *Converter) addHeading(node *ast.Heading, source []byte) {
 	level := node.Level
 	if level < 1 {
@@ -132,22 +352,24 @@ func (c *Converter) addHeading(node *ast.Heading, source []byte) {
 		level = 6
 	}
 
-	text := c.extractText(node, source)
-	size := headingSizes[level]
+	runs := c.processInlineNodes(node, source)
+
+	// Bookmark the heading so that #anchor links can target it
+	bookmarkStart, bookmarkEnd := "", ""
+	if id, ok := node.AttributeString("id"); ok {
+		if idBytes, ok := id.([]byte); ok && len(idBytes) > 0 {
+			bookmarkStart = fmt.Sprintf(`<w:bookmarkStart w:id="%d" w:name="%s"/>`, c.bookmarks, c.bookmarkFor(string(idBytes)))
+			bookmarkEnd = fmt.Sprintf(`<w:bookmarkEnd w:id="%d"/>`, c.bookmarks)
+			c.bookmarks++
+		}
+	}
 
 	para := fmt.Sprintf(`<w:p>
       <w:pPr>
-        <w:spacing w:after="120" w:before="240"/>
+        <w:pStyle w:val="Heading%d"/>
       </w:pPr>
-      <w:r>
-        <w:rPr>
-          <w:b/>
-          <w:sz w:val="%d"/>
-          <w:szCs w:val="%d"/>
-        </w:rPr>
-        <w:t xml:space="preserve">%s</w:t>
-      </w:r>
-    </w:p>`, size, size, escapeXML(text))
+      %s%s%s
+    </w:p>`, level, bookmarkStart, c.wrapRuns(runs), bookmarkEnd)
 
 	c.paragraphs = append(c.paragraphs, para)
 }
@@ -155,14 +377,13 @@ func (c *Converter) addHeading(node *ast.Heading, source []byte) {
 // addParagraph adds a paragraph to the document
 func (c *Converter) addParagraph(node *ast.Paragraph, source []byte) {
 	runs := c.processInlineNodes(node, source)
-	fontSize := int(c.opts.FontSize * 2) // Convert to half-points
 
 	para := fmt.Sprintf(`<w:p>
       <w:pPr>
-        <w:spacing w:after="160"/>
+        <w:pStyle w:val="BodyText"/>
       </w:pPr>
       %s
-    </w:p>`, c.wrapRuns(runs, fontSize))
+    </w:p>`, c.wrapRuns(runs))
 
 	c.paragraphs = append(c.paragraphs, para)
 }
@@ -172,58 +393,122 @@ type RunStyle struct {
 	Text      string
 	Bold      bool
 	Italic    bool
+	Strike    bool
 	Code      bool
 	Link      bool
 	LinkURL   string
 	Color     string
 	Highlight bool
+
+	// Break marks a hard line break rather than text
+	Break bool
+
+	// Drawing holds a prebuilt <w:drawing> element for inline images
+	Drawing string
+
+	// Math holds a prebuilt <m:oMath> element for inline equations
+	Math string
+
+	// Note holds a prebuilt run referencing a footnote or endnote
+	Note string
 }
 
 // processInlineNodes processes inline nodes and returns styled runs
 func (c *Converter) processInlineNodes(node ast.Node, source []byte) []RunStyle {
-	var runs []RunStyle
+	return c.inlineRuns(node, source, RunStyle{}, nil)
+}
 
+// inlineRuns walks inline nodes while accumulating formatting from enclosing
+// emphasis, code and link nodes, emitting one run per leaf
+func (c *Converter) inlineRuns(node ast.Node, source []byte, style RunStyle, runs []RunStyle) []RunStyle {
 	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
 		switch n := child.(type) {
 		case *ast.Text:
-			text := string(n.Segment.Value(source))
-			runs = append(runs, RunStyle{Text: text})
+			text := textValue(n, source)
+			if n.SoftLineBreak() && !n.HardLineBreak() {
+				text += " "
+			}
+			runs = append(runs, style.withText(text))
+			if n.HardLineBreak() {
+				runs = append(runs, RunStyle{Break: true})
+			}
 
 		case *ast.String:
-			runs = append(runs, RunStyle{Text: string(n.Value)})
+			runs = append(runs, style.withText(string(n.Value)))
 
 		case *ast.Emphasis:
-			text := c.extractText(n, source)
+			inner := style
 			if n.Level == 1 {
-				runs = append(runs, RunStyle{Text: text, Italic: true})
-			} else if n.Level == 2 {
-				runs = append(runs, RunStyle{Text: text, Bold: true})
+				inner.Italic = true
+			} else {
+				inner.Bold = true
 			}
+			runs = c.inlineRuns(n, source, inner, runs)
+
+		case *east.Strikethrough:
+			inner := style
+			inner.Strike = true
+			runs = c.inlineRuns(n, source, inner, runs)
+
+		case *east.TaskCheckBox:
+			box := "☐ "
+			if n.IsChecked {
+				box = "☑ "
+			}
+			runs = append(runs, style.withText(box))
 
 		case *ast.CodeSpan:
-			text := c.extractText(n, source)
-			runs = append(runs, RunStyle{Text: text, Code: true, Highlight: true})
+			inner := style
+			inner.Code = true
+			runs = append(runs, inner.withText(codeSpanText(n, source)))
 
 		case *ast.Link:
-			text := c.extractText(n, source)
-			url := string(n.Destination)
-			runs = append(runs, RunStyle{Text: text, Link: true, LinkURL: url, Color: "0000FF"})
+			inner := style
+			inner.Link = true
+			inner.LinkURL = string(n.Destination)
+			runs = c.inlineRuns(n, source, inner, runs)
 
 		case *ast.AutoLink:
 			url := string(n.URL(source))
-			runs = append(runs, RunStyle{Text: url, Link: true, LinkURL: url, Color: "0000FF"})
+			inner := style
+			inner.Link = true
+			inner.LinkURL = url
+			if n.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(strings.ToLower(url), "mailto:") {
+				inner.LinkURL = "mailto:" + url
+			}
+			runs = append(runs, inner.withText(string(n.Label(source))))
+
+		case *texmath.Inline:
+			if omml, ok := c.mathXML(string(n.TeX)); ok {
+				runs = append(runs, RunStyle{Math: "<m:oMath>" + omml + "</m:oMath>"})
+				continue
+			}
+			inner := style
+			inner.Code = true
+			runs = append(runs, inner.withText(string(n.TeX)))
+
+		case *east.FootnoteLink:
+			runs = append(runs, c.noteReference(n, source, style))
 
 		case *ast.Image:
 			altText := c.extractText(n, source)
 			if altText == "" {
 				altText = "Image"
 			}
-			runs = append(runs, RunStyle{Text: fmt.Sprintf("[%s]", altText), Italic: true, Color: "808080"})
+			drawing, err := c.embedImage(string(n.Destination), altText)
+			if err != nil {
+				c.warn("%v", err)
+				placeholder := style
+				placeholder.Italic = true
+				placeholder.Color = "808080"
+				runs = append(runs, placeholder.withText(fmt.Sprintf("[%s]", altText)))
+				continue
+			}
+			runs = append(runs, RunStyle{Drawing: drawing})
 
 		default:
 			if child.HasChildren() {
-				childRuns := c.processInlineNodes(child, source)
-				runs = append(runs, childRuns...)
+				runs = c.inlineRuns(child, source, style, runs)
 			}
 		}
 	}
@@ -231,48 +516,239 @@ func (c *Converter) processInlineNodes(node ast.Node, source []byte) []RunStyle
 	return runs
 }
 
-// wrapRuns creates XML for runs with the given styles
-func (c *Converter) wrapRuns(runs []RunStyle, defaultFontSize int) string {
+// withText returns a copy of the style carrying the given text
+func (s RunStyle) withText(text string) RunStyle {
+	s.Text = text
+	return s
+}
+
+// textValue returns the text of a node as it reads, with backslash escapes
+// and character references resolved. Raw text is returned as is.
+func textValue(node *ast.Text, source []byte) string {
+	value := node.Segment.Value(source)
+	if node.IsRaw() {
+		return string(value)
+	}
+	value = util.UnescapePunctuations(value)
+	value = util.ResolveNumericReferences(value)
+	value = util.ResolveEntityNames(value)
+	return string(value)
+}
+
+// codeSpanText returns the literal content of a code span
+func codeSpanText(node *ast.CodeSpan, source []byte) string {
+	var result strings.Builder
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch t := child.(type) {
+		case *ast.Text:
+			result.Write(t.Segment.Value(source))
+		case *ast.String:
+			result.Write(t.Value)
+		}
+	}
+	return result.String()
+}
+
+// wrapRuns creates XML for runs with the given styles. Consecutive runs
+// pointing at the same link target are grouped into one hyperlink.
+func (c *Converter) wrapRuns(runs []RunStyle) string {
 	var result strings.Builder
-	codeFontSize := int(c.opts.CodeFontSize * 2)
 
-	for _, run := range runs {
-		fontSize := defaultFontSize
-		if run.Code {
-			fontSize = codeFontSize
+	for i := 0; i < len(runs); {
+		run := runs[i]
+		if !run.Link || run.LinkURL == "" {
+			result.WriteString(c.runXML(run))
+			i++
+			continue
 		}
 
-		result.WriteString("<w:r><w:rPr>")
+		j := i
+		var linkRuns strings.Builder
+		for ; j < len(runs) && runs[j].Link && runs[j].LinkURL == run.LinkURL; j++ {
+			linkRuns.WriteString(c.runXML(runs[j]))
+		}
+		result.WriteString(c.hyperlinkXML(run.LinkURL, linkRuns.String()))
+		i = j
+	}
+
+	return result.String()
+}
+
+// hyperlinkXML wraps runs in a <w:hyperlink>. Fragment-only targets jump to
+// the bookmark of the matching heading; everything else becomes an external
+// relationship.
+func (c *Converter) hyperlinkXML(target, runs string) string {
+	if strings.HasPrefix(target, "#") {
+		return fmt.Sprintf(`<w:hyperlink w:anchor="%s" w:history="1">%s</w:hyperlink>`,
+			c.bookmarkFor(target[1:]), runs)
+	}
+
+	id, ok := c.hyperlinks[target]
+	if !ok {
+		id = c.addRelationship(relTypeHyperlink, target, true)
+		c.hyperlinks[target] = id
+	}
+	return fmt.Sprintf(`<w:hyperlink r:id="%s" w:history="1">%s</w:hyperlink>`, id, runs)
+}
 
-		if run.Bold {
-			result.WriteString("<w:b/>")
+// runXML creates XML for a single run. Code and links use the VerbatimChar
+// and Hyperlink character styles; other attributes are direct formatting.
+func (c *Converter) runXML(run RunStyle) string {
+	if run.Drawing != "" {
+		return "<w:r>" + run.Drawing + "</w:r>"
+	}
+	if run.Math != "" {
+		return run.Math
+	}
+	if run.Note != "" {
+		return run.Note
+	}
+	if run.Break {
+		return "<w:r><w:br/></w:r>"
+	}
+
+	var rPr strings.Builder
+	if run.Code {
+		rPr.WriteString(`<w:rStyle w:val="VerbatimChar"/>`)
+	} else if run.Link {
+		rPr.WriteString(`<w:rStyle w:val="Hyperlink"/>`)
+	}
+	if run.Bold {
+		rPr.WriteString("<w:b/>")
+	}
+	if run.Italic {
+		rPr.WriteString("<w:i/>")
+	}
+	if run.Strike {
+		rPr.WriteString("<w:strike/>")
+	}
+	if run.Color != "" {
+		rPr.WriteString(fmt.Sprintf(`<w:color w:val="%s"/>`, run.Color))
+	}
+	if run.Link && run.Code {
+		// Code inside a link keeps the code font but still looks like a link
+		if run.Color == "" {
+			rPr.WriteString(`<w:color w:val="0563C1"/>`)
 		}
-		if run.Italic {
-			result.WriteString("<w:i/>")
+		rPr.WriteString(`<w:u w:val="single"/>`)
+	}
+	if run.Highlight {
+		rPr.WriteString(`<w:highlight w:val="lightGray"/>`)
+	}
+
+	var result strings.Builder
+	result.WriteString("<w:r>")
+	if rPr.Len() > 0 {
+		result.WriteString("<w:rPr>" + rPr.String() + "</w:rPr>")
+	}
+	result.WriteString(fmt.Sprintf(`<w:t xml:space="preserve">%s</w:t>`, escapeXML(run.Text)))
+	result.WriteString("</w:r>")
+
+	return result.String()
+}
+
+// collectBookmarks gives every heading ID a unique bookmark name up front,
+// so that links can jump to headings further down. Names that collide once
+// truncated get a numeric suffix.
+func (c *Converter) collectBookmarks(root ast.Node) {
+	c.bookmarkNames = map[string]string{}
+	used := map[string]bool{}
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
 		}
-		if run.Color != "" {
-			result.WriteString(fmt.Sprintf(`<w:color w:val="%s"/>`, run.Color))
+		id, ok := heading.AttributeString("id")
+		idBytes, isBytes := id.([]byte)
+		if !ok || !isBytes || len(idBytes) == 0 {
+			return ast.WalkSkipChildren, nil
 		}
-		if run.Link {
-			result.WriteString(`<w:u w:val="single"/>`)
+		if _, seen := c.bookmarkNames[string(idBytes)]; seen {
+			return ast.WalkSkipChildren, nil
 		}
-		if run.Highlight {
-			result.WriteString(`<w:highlight w:val="lightGray"/>`)
+
+		base := bookmarkName(string(idBytes))
+		name := base
+		for i := 1; used[name]; i++ {
+			suffix := fmt.Sprintf("_%d", i)
+			prefix := []rune(base)
+			if len(prefix)+len(suffix) > maxBookmarkLength {
+				prefix = prefix[:maxBookmarkLength-len(suffix)]
+			}
+			name = string(prefix) + suffix
 		}
-		if run.Code {
-			result.WriteString(`<w:rFonts w:ascii="Consolas" w:hAnsi="Consolas"/>`)
+		used[name] = true
+		c.bookmarkNames[string(idBytes)] = name
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// bookmarkFor returns the bookmark name of a heading ID
+func (c *Converter) bookmarkFor(id string) string {
+	if name, ok := c.bookmarkNames[id]; ok {
+		return name
+	}
+	return bookmarkName(id)
+}
+
+// Word limits bookmark names to 40 characters
+const maxBookmarkLength = 40
+
+// bookmarkName converts a heading ID into a valid Word bookmark name. Names
+// start with an underscore so Word treats them as hidden bookmarks.
+func bookmarkName(id string) string {
+	var name strings.Builder
+	name.WriteString("_")
+	for _, r := range id {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			name.WriteRune(r)
+		} else {
+			name.WriteRune('_')
 		}
+	}
 
-		result.WriteString(fmt.Sprintf(`<w:sz w:val="%d"/><w:szCs w:val="%d"/>`, fontSize, fontSize))
-		result.WriteString("</w:rPr>")
-		result.WriteString(fmt.Sprintf(`<w:t xml:space="preserve">%s</w:t>`, escapeXML(run.Text)))
-		result.WriteString("</w:r>")
+	result := []rune(name.String())
+	if len(result) > maxBookmarkLength {
+		result = result[:maxBookmarkLength]
 	}
+	return string(result)
+}
 
-	return result.String()
+// addMathBlock adds display math as an equation paragraph, or as code when
+// it can't be translated
+func (c *Converter) addMathBlock(node *texmath.Block, source []byte) {
+	var tex strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		tex.Write(segment.Value(source))
+	}
+
+	content := `<w:r><w:rPr><w:rStyle w:val="VerbatimChar"/></w:rPr><w:t xml:space="preserve">` + escapeXML(strings.TrimSpace(tex.String())) + `</w:t></w:r>`
+	if omml, ok := c.mathXML(tex.String()); ok {
+		content = "<m:oMathPara><m:oMath>" + omml + "</m:oMath></m:oMathPara>"
+	}
+
+	c.paragraphs = append(c.paragraphs, fmt.Sprintf(`<w:p>
+      <w:pPr>
+        <w:pStyle w:val="BodyText"/>
+      </w:pPr>
+      %s
+    </w:p>`, content))
+}
+
+// mathXML translates TeX math into OMML, warning when it can't
+func (c *Converter) mathXML(tex string) (string, bool) {
+	omml, err := texToOMML(tex)
+	if err != nil {
+		c.warn("math \"%s\" kept as text: %v", strings.TrimSpace(tex), err)
+		return "", false
+	}
+	return omml, true
 }
 
-// addCodeBlock adds a code block to the document
+// addCodeBlock adds a code block to the document, highlighting fenced code
+// according to its language
 func (c *Converter) addCodeBlock(node ast.Node, source []byte) {
 	var codeText string
 	lines := node.Lines()
@@ -282,28 +758,36 @@ func (c *Converter) addCodeBlock(node ast.Node, source []byte) {
 	}
 
 	codeText = strings.TrimRight(codeText, "\n")
-	codeLines := strings.Split(codeText, "\n")
-	codeFontSize := int(c.opts.CodeFontSize * 2)
 
-	for _, line := range codeLines {
-		if line == "" {
-			line = " "
+	language := ""
+	if fenced, ok := node.(*ast.FencedCodeBlock); ok {
+		language = string(fenced.Language(source))
+	}
+
+	// Blocks with a renderer become images, or stay code if rendering fails
+	if r := c.fenceRenderer(language); r != nil && c.addFenceImage(language, r, codeText) {
+		return
+	}
+
+	shading := ""
+	if fill := c.codeBackground(); fill != "" {
+		shading = fmt.Sprintf(`<w:shd w:val="clear" w:color="auto" w:fill="%s"/>`, fill)
+	}
+
+	codeLines := c.highlightCode(language, codeText)
+	for i, runs := range codeLines {
+		if len(runs) == 0 {
+			runs = []RunStyle{{Text: " "}}
+		}
+		if c.opts.LineNumbers {
+			runs = append([]RunStyle{lineNumberRun(i+1, len(codeLines))}, runs...)
 		}
 		para := fmt.Sprintf(`<w:p>
       <w:pPr>
-        <w:shd w:val="clear" w:color="auto" w:fill="F6F8FA"/>
-        <w:ind w:left="360"/>
-        <w:spacing w:after="0"/>
+        <w:pStyle w:val="SourceCode"/>%s
       </w:pPr>
-      <w:r>
-        <w:rPr>
-          <w:rFonts w:ascii="Consolas" w:hAnsi="Consolas"/>
-          <w:sz w:val="%d"/>
-          <w:szCs w:val="%d"/>
-        </w:rPr>
-        <w:t xml:space="preserve">%s</w:t>
-      </w:r>
-    </w:p>`, codeFontSize, codeFontSize, escapeXML(line))
+      %s
+    </w:p>`, shading, c.wrapRuns(runs))
 		c.paragraphs = append(c.paragraphs, para)
 	}
 
@@ -311,104 +795,192 @@ func (c *Converter) addCodeBlock(node ast.Node, source []byte) {
 	c.paragraphs = append(c.paragraphs, `<w:p><w:pPr><w:spacing w:after="160"/></w:pPr></w:p>`)
 }
 
-// addList adds a list to the document
+// addList adds a list to the document. Every list gets its own numbering
+// instance so that numbering restarts with each list.
 func (c *Converter) addList(node *ast.List, source []byte, level int) {
-	isOrdered := node.IsOrdered()
-	itemNum := 1
+	numID := c.addNumbering(node, level)
 
 	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
 		if listItem, ok := child.(*ast.ListItem); ok {
-			c.addListItem(listItem, source, level, isOrdered, itemNum)
-			itemNum++
+			c.addListItem(listItem, source, level, numID, node.IsTight)
 		}
 	}
 }
 
-// addListItem adds a list item to the document
-func (c *Converter) addListItem(node *ast.ListItem, source []byte, level int, isOrdered bool, itemNum int) {
-	indent := 360 + level*360 // In twips (1/20 of a point)
-	fontSize := int(c.opts.FontSize * 2)
+// addListItem adds a list item to the document. The first paragraph carries
+// the bullet or number; further paragraphs are indented continuations.
+func (c *Converter) addListItem(node *ast.ListItem, source []byte, level, numID int, tight bool) {
+	// ListParagraph spacing suits tight lists; loose lists get paragraph spacing
+	spacing := ""
+	if !tight {
+		spacing = `<w:spacing w:after="160"/>`
+	}
 
-	bullet := "• "
-	if isOrdered {
-		bullet = fmt.Sprintf("%d. ", itemNum)
+	first := true
+	addItemParagraph := func(runs []RunStyle) {
+		pPr := fmt.Sprintf(`<w:ind w:left="%d"/>`, listIndent(level))
+		if first {
+			pPr = fmt.Sprintf(`<w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr>`, clampListLevel(level), numID)
+			first = false
+		}
+		para := fmt.Sprintf(`<w:p>
+      <w:pPr>
+        <w:pStyle w:val="ListParagraph"/>
+        %s%s
+      </w:pPr>
+      %s
+    </w:p>`, pPr, spacing, c.wrapRuns(runs))
+		c.paragraphs = append(c.paragraphs, para)
 	}
 
-	var contentRuns []RunStyle
 	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
 		switch n := child.(type) {
 		case *ast.TextBlock:
-			contentRuns = append(contentRuns, c.processInlineNodes(n, source)...)
+			addItemParagraph(c.processInlineNodes(n, source))
 		case *ast.Paragraph:
-			contentRuns = append(contentRuns, c.processInlineNodes(n, source)...)
+			addItemParagraph(c.processInlineNodes(n, source))
 		case *ast.List:
-			// First add current item, then nested list
-			para := fmt.Sprintf(`<w:p>
-      <w:pPr>
-        <w:ind w:left="%d"/>
-        <w:spacing w:after="80"/>
-      </w:pPr>
-      <w:r>
-        <w:rPr>
-          <w:sz w:val="%d"/>
-          <w:szCs w:val="%d"/>
-        </w:rPr>
-        <w:t xml:space="preserve">%s</w:t>
-      </w:r>
-      %s
-    </w:p>`, indent, fontSize, fontSize, escapeXML(bullet), c.wrapRuns(contentRuns, fontSize))
-			c.paragraphs = append(c.paragraphs, para)
+			if first {
+				addItemParagraph(nil)
+			}
 			c.addList(n, source, level+1)
-			return
+		default:
+			if first {
+				addItemParagraph(nil)
+			}
+			c.processBlock(child, source)
 		}
 	}
 
-	para := fmt.Sprintf(`<w:p>
-      <w:pPr>
-        <w:ind w:left="%d"/>
-        <w:spacing w:after="80"/>
-      </w:pPr>
-      <w:r>
-        <w:rPr>
-          <w:sz w:val="%d"/>
-          <w:szCs w:val="%d"/>
-        </w:rPr>
-        <w:t xml:space="preserve">%s</w:t>
-      </w:r>
-      %s
-    </w:p>`, indent, fontSize, fontSize, escapeXML(bullet), c.wrapRuns(contentRuns, fontSize))
-	c.paragraphs = append(c.paragraphs, para)
+	// Empty list items still get their bullet or number
+	if first {
+		addItemParagraph(nil)
+	}
 }
 
 // addBlockquote adds a blockquote to the document
 func (c *Converter) addBlockquote(node *ast.Blockquote, source []byte) {
-	fontSize := int(c.opts.FontSize * 2)
-
 	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
 		if para, ok := child.(*ast.Paragraph); ok {
 			runs := c.processInlineNodes(para, source)
 
-			// Style all runs as italic and gray
-			for i := range runs {
-				runs[i].Italic = true
-				runs[i].Color = "6A737D"
-			}
-
 			p := fmt.Sprintf(`<w:p>
       <w:pPr>
-        <w:ind w:left="720"/>
-        <w:pBdr>
-          <w:left w:val="single" w:sz="24" w:space="4" w:color="DFE2E5"/>
-        </w:pBdr>
-        <w:spacing w:after="160"/>
+        <w:pStyle w:val="BlockQuote"/>
       </w:pPr>
       %s
-    </w:p>`, c.wrapRuns(runs, fontSize))
+    </w:p>`, c.wrapRuns(runs))
 			c.paragraphs = append(c.paragraphs, p)
 		}
 	}
 }
 
+// addTable adds a GFM table to the document
+func (c *Converter) addTable(node *east.Table, source []byte) {
+	columns := len(node.Alignments)
+	if columns == 0 {
+		return
+	}
+
+	// Distribute the usable page width evenly across columns (in twips)
+	colWidth := c.contentWidth() / columns
+
+	var tbl strings.Builder
+	tbl.WriteString(`<w:tbl>
+      <w:tblPr>
+        <w:tblStyle w:val="TableGrid"/>
+        <w:tblW w:w="0" w:type="auto"/>
+        <w:tblLook w:val="04A0" w:firstRow="1" w:lastRow="0" w:firstColumn="0" w:lastColumn="0" w:noHBand="0" w:noVBand="1"/>
+      </w:tblPr>
+      <w:tblGrid>`)
+	for i := 0; i < columns; i++ {
+		tbl.WriteString(fmt.Sprintf(`<w:gridCol w:w="%d"/>`, colWidth))
+	}
+	tbl.WriteString(`</w:tblGrid>`)
+
+	for row := node.FirstChild(); row != nil; row = row.NextSibling() {
+		switch row.(type) {
+		case *east.TableHeader:
+			tbl.WriteString(c.tableRow(row, source, node.Alignments, colWidth, true))
+		case *east.TableRow:
+			tbl.WriteString(c.tableRow(row, source, node.Alignments, colWidth, false))
+		}
+	}
+	tbl.WriteString(`
+    </w:tbl>`)
+
+	c.paragraphs = append(c.paragraphs, tbl.String())
+
+	// Add spacing after table
+	c.paragraphs = append(c.paragraphs, `<w:p><w:pPr><w:spacing w:after="160"/></w:pPr></w:p>`)
+}
+
+// tableRow creates XML for a table row. Header rows are repeated at the top
+// of every page the table spans.
+func (c *Converter) tableRow(row ast.Node, source []byte, alignments []east.Alignment, colWidth int, header bool) string {
+	var tr strings.Builder
+	tr.WriteString(`
+      <w:tr>`)
+	if header {
+		tr.WriteString(`<w:trPr><w:tblHeader/></w:trPr>`)
+	}
+
+	col := 0
+	for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+		tc, ok := cell.(*east.TableCell)
+		if !ok {
+			continue
+		}
+
+		alignment := tc.Alignment
+		if col < len(alignments) {
+			alignment = alignments[col]
+		}
+
+		runs := c.processInlineNodes(tc, source)
+		if header {
+			for i := range runs {
+				runs[i].Bold = true
+			}
+		}
+
+		tr.WriteString(fmt.Sprintf(`<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>`, colWidth))
+		if header {
+			tr.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="F6F8FA"/>`)
+		}
+		tr.WriteString(`</w:tcPr><w:p><w:pPr><w:spacing w:before="40" w:after="40"/>`)
+		if jc := tableAlignment(alignment); jc != "" {
+			tr.WriteString(fmt.Sprintf(`<w:jc w:val="%s"/>`, jc))
+		}
+		tr.WriteString(`</w:pPr>`)
+		tr.WriteString(c.wrapRuns(runs))
+		tr.WriteString(`</w:p></w:tc>`)
+		col++
+	}
+
+	// Pad short rows so every row has the same number of cells
+	for ; col < len(alignments); col++ {
+		tr.WriteString(fmt.Sprintf(`<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/></w:tcPr><w:p/></w:tc>`, colWidth))
+	}
+
+	tr.WriteString(`</w:tr>`)
+	return tr.String()
+}
+
+// tableAlignment maps a GFM column alignment to a Word justification value
+func tableAlignment(alignment east.Alignment) string {
+	switch alignment {
+	case east.AlignLeft:
+		return "left"
+	case east.AlignRight:
+		return "right"
+	case east.AlignCenter:
+		return "center"
+	default:
+		return ""
+	}
+}
+
 // addHorizontalRule adds a horizontal rule to the document
 func (c *Converter) addHorizontalRule() {
 	para := `<w:p>
@@ -433,7 +1005,7 @@ func (c *Converter) extractText(node ast.Node, source []byte) string {
 
 		switch t := n.(type) {
 		case *ast.Text:
-			result.Write(t.Segment.Value(source))
+			result.WriteString(textValue(t, source))
 		case *ast.String:
 			result.Write(t.Value)
 		case *ast.CodeSpan:
@@ -452,28 +1024,60 @@ func (c *Converter) extractText(node ast.Node, source []byte) string {
 	return strings.TrimSpace(text)
 }
 
-// createDocx creates a docx file with the processed content
-func (c *Converter) createDocx(outputPath string) error {
+// createDocx packages the processed content as a docx file
+func (c *Converter) createDocx() ([]byte, error) {
 	buf := new(bytes.Buffer)
 	w := zip.NewWriter(buf)
 
-	// Get page dimensions
-	pageWidth, pageHeight := c.getPageDimensions()
+	var err error
+	if c.reference != nil {
+		err = c.writeReferenceParts(w)
+	} else {
+		err = c.writeParts(w)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	// Margins in twips (1/20 of a point, 1 inch = 1440 twips)
-	marginTop := int(c.opts.MarginTop * 1440)
-	marginBottom := int(c.opts.MarginBottom * 1440)
-	marginLeft := int(c.opts.MarginLeft * 1440)
-	marginRight := int(c.opts.MarginRight * 1440)
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
 
+	return buf.Bytes(), nil
+}
+
+// writeParts writes a complete package generated from the converter options
+func (c *Converter) writeParts(w *zip.Writer) error {
 	// [Content_Types].xml
-	contentTypes := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+	var partTypes strings.Builder
+	seenExt := map[string]bool{}
+	for _, m := range c.media {
+		if seenExt[m.Ext] {
+			continue
+		}
+		seenExt[m.Ext] = true
+		partTypes.WriteString(fmt.Sprintf(`
+  <Default Extension="%s" ContentType="%s"/>`, m.Ext, m.ContentType))
+	}
+	if len(c.numbering) > 0 {
+		partTypes.WriteString(`
+  <Override PartName="/word/numbering.xml" ContentType="` + contentTypeNumbering + `"/>`)
+	}
+	notes := c.noteKind()
+	if len(c.notes) > 0 {
+		partTypes.WriteString(`
+  <Override PartName="/` + notes.part() + `" ContentType="` + notes.ContentType + `"/>`)
+	}
+
+	contentTypes := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
 <Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
   <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
   <Default Extension="xml" ContentType="application/xml"/>
   <Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
-  <Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
-</Types>`
+  <Override PartName="/word/styles.xml" ContentType="%s"/>
+  <Override PartName="/%s" ContentType="%s"/>
+  <Override PartName="/%s" ContentType="%s"/>%s
+</Types>`, contentTypeStyles, corePropsPart, contentTypeCoreProps, appPropsPart, contentTypeAppProps, partTypes.String())
 
 	if err := addFileToZip(w, "[Content_Types].xml", contentTypes); err != nil {
 		return err
@@ -483,63 +1087,133 @@ func (c *Converter) createDocx(outputPath string) error {
 	rels := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
 <Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
   <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+  <Relationship Id="rId2" Type="` + relTypeCoreProps + `" Target="` + corePropsPart + `"/>
+  <Relationship Id="rId3" Type="` + relTypeAppProps + `" Target="` + appPropsPart + `"/>
 </Relationships>`
 
 	if err := addFileToZip(w, "_rels/.rels", rels); err != nil {
 		return err
 	}
 
+	// docProps/core.xml and docProps/app.xml
+	if err := addFileToZip(w, corePropsPart, c.corePropsXML()); err != nil {
+		return err
+	}
+	if err := addFileToZip(w, appPropsPart, c.appPropsXML()); err != nil {
+		return err
+	}
+
 	// word/_rels/document.xml.rels
+	c.addRelationship(relTypeStyles, "styles.xml", false)
+	if len(c.numbering) > 0 {
+		c.addRelationship(relTypeNumbering, "numbering.xml", false)
+	}
+	if len(c.notes) > 0 {
+		c.addRelationship(notes.RelType, notes.target(), false)
+	}
+
 	docRels := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
-<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
-  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + relationshipsXML(c.relationships) + `
 </Relationships>`
 
 	if err := addFileToZip(w, "word/_rels/document.xml.rels", docRels); err != nil {
 		return err
 	}
 
+	// word/media/*
+	if err := c.writeMedia(w); err != nil {
+		return err
+	}
+
+	// word/numbering.xml
+	if len(c.numbering) > 0 {
+		if err := addFileToZip(w, "word/numbering.xml", c.numberingXML()); err != nil {
+			return err
+		}
+	}
+
+	// word/footnotes.xml or word/endnotes.xml
+	if len(c.notes) > 0 {
+		if err := addFileToZip(w, notes.part(), c.notesXML("")); err != nil {
+			return err
+		}
+	}
+	if len(c.noteRels) > 0 {
+		if err := addFileToZip(w, notes.relsPart(), c.noteRelsXML()); err != nil {
+			return err
+		}
+	}
+
 	// word/styles.xml
-	defaultFontSize := int(c.opts.FontSize * 2)
-	styles := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
-<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
-  <w:docDefaults>
-    <w:rPrDefault>
-      <w:rPr>
-        <w:rFonts w:ascii="%s" w:hAnsi="%s"/>
-        <w:sz w:val="%d"/>
-        <w:szCs w:val="%d"/>
-      </w:rPr>
-    </w:rPrDefault>
-  </w:docDefaults>
-</w:styles>`, c.opts.FontFamily, c.opts.FontFamily, defaultFontSize, defaultFontSize)
-
-	if err := addFileToZip(w, "word/styles.xml", styles); err != nil {
+	if err := addFileToZip(w, "word/styles.xml", c.stylesXML()); err != nil {
 		return err
 	}
 
 	// word/document.xml
+	return addFileToZip(w, "word/document.xml", c.documentXML(documentStartTag, c.sectPrXML()))
+}
+
+// Content types of optional package parts
+const (
+	contentTypeStyles    = "application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"
+	contentTypeNumbering = "application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"
+)
+
+// documentStartTag declares the namespaces used by generated body content
+const documentStartTag = `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture" xmlns:m="http://schemas.openxmlformats.org/officeDocument/2006/math">`
+
+// documentXML creates word/document.xml from the processed paragraphs
+func (c *Converter) documentXML(startTag, sectPr string) string {
 	documentContent := strings.Join(c.paragraphs, "\n    ")
-	document := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
-<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+%s
   <w:body>
     %s
-    <w:sectPr>
+    %s
+  </w:body>
+</w:document>`, startTag, documentContent, sectPr)
+}
+
+// sectPrXML creates the section properties for the configured page size
+// and margins
+func (c *Converter) sectPrXML() string {
+	// Get page dimensions
+	pageWidth, pageHeight := c.getPageDimensions()
+
+	// Margins in twips (1/20 of a point, 1 inch = 1440 twips)
+	marginTop := int(c.opts.MarginTop * 1440)
+	marginBottom := int(c.opts.MarginBottom * 1440)
+	marginLeft := int(c.opts.MarginLeft * 1440)
+	marginRight := int(c.opts.MarginRight * 1440)
+
+	return fmt.Sprintf(`<w:sectPr>
       <w:pgSz w:w="%d" w:h="%d"/>
       <w:pgMar w:top="%d" w:right="%d" w:bottom="%d" w:left="%d" w:header="720" w:footer="720" w:gutter="0"/>
-    </w:sectPr>
-  </w:body>
-</w:document>`, documentContent, pageWidth, pageHeight, marginTop, marginRight, marginBottom, marginLeft)
+    </w:sectPr>`, pageWidth, pageHeight, marginTop, marginRight, marginBottom, marginLeft)
+}
 
-	if err := addFileToZip(w, "word/document.xml", document); err != nil {
-		return err
+// relationshipsXML creates the <Relationship> entries collected during conversion
+func relationshipsXML(rels []relationship) string {
+	var result strings.Builder
+	for _, rel := range rels {
+		result.WriteString(fmt.Sprintf(`
+  <Relationship Id="%s" Type="%s" Target="%s"`, rel.ID, rel.Type, escapeXML(rel.Target)))
+		if rel.TargetMode != "" {
+			result.WriteString(fmt.Sprintf(` TargetMode="%s"`, rel.TargetMode))
+		}
+		result.WriteString("/>")
 	}
+	return result.String()
+}
 
-	if err := w.Close(); err != nil {
-		return err
+// writeMedia adds the embedded images to the package
+func (c *Converter) writeMedia(w *zip.Writer) error {
+	for _, m := range c.media {
+		if err := addFileToZip(w, "word/media/"+m.Name, string(m.Data)); err != nil {
+			return err
+		}
 	}
-
-	return os.WriteFile(outputPath, buf.Bytes(), 0644)
+	return nil
 }
 
 // getPageDimensions returns page width and height in twips
@@ -554,6 +1228,15 @@ func (c *Converter) getPageDimensions() (int, int) {
 	}
 }
 
+// contentWidth returns the usable page width between the margins in twips
+func (c *Converter) contentWidth() int {
+	if c.reference != nil && c.reference.contentWidth > 0 {
+		return c.reference.contentWidth
+	}
+	pageWidth, _ := c.getPageDimensions()
+	return pageWidth - int(c.opts.MarginLeft*1440) - int(c.opts.MarginRight*1440)
+}
+
 // addFileToZip adds a file with the given content to the zip writer
 func addFileToZip(w *zip.Writer, name, content string) error {
 	f, err := w.Create(name)
@@ -0,0 +1,351 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// convertParts converts markdown and returns the parts of the resulting
+// package by name
+func convertParts(t *testing.T, markdown string, opts Options) map[string]string {
+	t.Helper()
+	parts, _ := convertWithWarnings(t, markdown, opts)
+	return parts
+}
+
+// convertWithWarnings converts markdown and returns the parts of the
+// resulting package by name and the conversion warnings
+func convertWithWarnings(t *testing.T, markdown string, opts Options) (map[string]string, []string) {
+	t.Helper()
+
+	if opts.FontSize == 0 {
+		opts.FontSize = 11
+	}
+	if opts.CodeFontSize == 0 {
+		opts.CodeFontSize = 10
+	}
+
+	var buf bytes.Buffer
+	c := New(opts)
+	if err := c.ConvertTo([]byte(markdown), &buf); err != nil {
+		t.Fatal(err)
+	}
+	return unzipParts(t, buf.Bytes()), c.Warnings()
+}
+
+// unzipParts returns the parts of a package by name
+func unzipParts(t *testing.T, docx []byte) map[string]string {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(docx), int64(len(docx)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = string(data)
+	}
+	return parts
+}
+
+// pngImage returns a PNG image of the given size
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReusedConverterForgetsFileDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "pic.png"), pngImage(t, 4, 4), 0644); err != nil {
+		t.Fatal(err)
+	}
+	markdown := "![](pic.png)\n"
+	input := filepath.Join(dir, "doc.md")
+	if err := os.WriteFile(input, []byte(markdown), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(Options{FontSize: 11, CodeFontSize: 10})
+	var buf bytes.Buffer
+	if err := c.ConvertFileTo(input, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Warnings()) != 0 {
+		t.Fatalf("file: got warnings %q", c.Warnings())
+	}
+
+	// The image is next to the file, not in the current directory
+	want := []string{"image not found: pic.png"}
+	convert := map[string]func() error{
+		"ConvertTo":     func() error { return c.ConvertTo([]byte(markdown), io.Discard) },
+		"ConvertReader": func() error { return c.ConvertReader(strings.NewReader(markdown), io.Discard) },
+		"Convert":       func() error { return c.Convert([]byte(markdown), filepath.Join(dir, "out.docx")) },
+	}
+	for name, fn := range convert {
+		if err := c.ConvertFileTo(input, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if err := fn(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(c.Warnings(), want) {
+			t.Errorf("%s: got warnings %q, want %q", name, c.Warnings(), want)
+		}
+	}
+}
+
+func TestBookmarkNamesAreUnique(t *testing.T) {
+	long := "A very long heading that shares its first forty characters"
+	markdown := "# " + long + " with one ending\n\n# " + long + " with another ending\n\n" +
+		"[first](#a-very-long-heading-that-shares-its-first-forty-characters-with-one-ending) " +
+		"[second](#a-very-long-heading-that-shares-its-first-forty-characters-with-another-ending)\n"
+
+	document := convertParts(t, markdown, Options{})["word/document.xml"]
+
+	names := regexp.MustCompile(`<w:bookmarkStart w:id="\d+" w:name="([^"]+)"/>`).FindAllStringSubmatch(document, -1)
+	if len(names) != 2 {
+		t.Fatalf("got %d bookmarks, want 2", len(names))
+	}
+	first, second := names[0][1], names[1][1]
+	if first == second {
+		t.Fatalf("both headings have bookmark %q", first)
+	}
+	for _, name := range []string{first, second} {
+		if n := len([]rune(name)); n > maxBookmarkLength {
+			t.Errorf("bookmark %q is %d characters long", name, n)
+		}
+	}
+
+	anchors := regexp.MustCompile(`<w:hyperlink w:anchor="([^"]+)"`).FindAllStringSubmatch(document, -1)
+	if len(anchors) != 2 || anchors[0][1] != first || anchors[1][1] != second {
+		t.Errorf("links jump to %v, want %q and %q", anchors, first, second)
+	}
+}
+
+// noteBodies returns the notes of a notes part by ID, leaving out the
+// separators
+func noteBodies(part, kind string) map[string]string {
+	notes := map[string]string{}
+	pattern := regexp.MustCompile(`(?s)<w:` + kind + ` w:id="(\d+)">(.*?)</w:` + kind + `>`)
+	for _, m := range pattern.FindAllStringSubmatch(part, -1) {
+		notes[m[1]] = m[2]
+	}
+	return notes
+}
+
+// noteRefs returns the bookmarks and cached numbers of the NOTEREF fields
+// in a part
+func noteRefs(part string) []string {
+	var refs []string
+	pattern := regexp.MustCompile(`NOTEREF (\S+) \\f \\h </w:instrText></w:r>.*?<w:t>([^<]*)</w:t>`)
+	for _, m := range pattern.FindAllStringSubmatch(part, -1) {
+		refs = append(refs, m[1]+"="+m[2])
+	}
+	return refs
+}
+
+func TestFootnotesCitedTwice(t *testing.T) {
+	markdown := "One[^a] two[^b] again[^a].\n\n[^a]: Note A cites[^b].\n[^b]: Note B.\n"
+
+	tests := []struct {
+		name   string
+		opts   Options
+		kind   string
+		second string // The number Word shows for the second note
+	}{
+		{"footnotes", Options{}, "footnote", "2"},
+		{"endnotes", Options{Endnotes: true}, "endnote", "ii"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := convertParts(t, markdown, tt.opts)
+			for name, part := range parts {
+				if err := xml.Unmarshal([]byte(part), new(struct{})); err != nil {
+					t.Errorf("%s is not well-formed: %v", name, err)
+				}
+			}
+
+			notes := noteBodies(parts["word/"+tt.kind+"s.xml"], tt.kind)
+			if len(notes) != 2 {
+				t.Fatalf("got %d notes, want one per footnote: %v", len(notes), notes)
+			}
+			if !strings.Contains(notes["1"], "Note A cites") || !strings.Contains(notes["2"], " B.") {
+				t.Errorf("notes have the wrong content: %v", notes)
+			}
+			if got := noteRefs(notes["1"]); !reflect.DeepEqual(got, []string{"_Note2=" + tt.second}) {
+				t.Errorf("note 1 cites %v, want note 2", got)
+			}
+
+			document := parts["word/document.xml"]
+			references := regexp.MustCompile(`<w:bookmarkStart w:id="\d+" w:name="(_Note\d)"/><w:r><w:rPr><w:rStyle w:val="\w+"/></w:rPr><w:`+tt.kind+`Reference w:id="(\d+)"/></w:r><w:bookmarkEnd`).FindAllStringSubmatch(document, -1)
+			if len(references) != 2 || references[0][1] != "_Note1" || references[0][2] != "1" || references[1][1] != "_Note2" || references[1][2] != "2" {
+				t.Errorf("got note references %v, want bookmarked references to notes 1 and 2", references)
+			}
+			first := "1"
+			if tt.opts.Endnotes {
+				first = "i"
+			}
+			if got := noteRefs(document); !reflect.DeepEqual(got, []string{"_Note1=" + first}) {
+				t.Errorf("repeat citation is %v, want a reference to note 1", got)
+			}
+
+			other := "footnote"
+			if tt.kind == "footnote" {
+				other = "endnote"
+			}
+			if _, ok := parts["word/"+other+"s.xml"]; ok {
+				t.Errorf("document has %ss too", other)
+			}
+		})
+	}
+}
+
+func TestFootnoteCitedOnlyFromFootnotes(t *testing.T) {
+	markdown := "Text[^a].\n\n[^a]: See[^b].\n[^b]: Hidden.\n"
+
+	var buf bytes.Buffer
+	c := New(Options{FontSize: 11, CodeFontSize: 10})
+	if err := c.ConvertTo([]byte(markdown), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Warnings()) != 1 || !strings.Contains(c.Warnings()[0], "only cited from other footnotes") {
+		t.Errorf("got warnings %q", c.Warnings())
+	}
+}
+
+// wordRun is a text run of a document part
+type wordRun struct {
+	Style *struct {
+		Val string `xml:"val,attr"`
+	} `xml:"rPr>rStyle"`
+	Bold   *struct{} `xml:"rPr>b"`
+	Italic *struct{} `xml:"rPr>i"`
+	Strike *struct{} `xml:"rPr>strike"`
+	Text   string    `xml:"t"`
+}
+
+// format summarizes the formatting of a run, as in "VerbatimChar:b"
+func (r wordRun) format() string {
+	var format []string
+	if r.Style != nil {
+		format = append(format, r.Style.Val)
+	}
+	if r.Bold != nil {
+		format = append(format, "b")
+	}
+	if r.Italic != nil {
+		format = append(format, "i")
+	}
+	if r.Strike != nil {
+		format = append(format, "strike")
+	}
+	return strings.Join(format, ":")
+}
+
+// describeRuns joins neighbouring runs of the same formatting, which the
+// converter splits at word boundaries, and describes them as "format:text"
+func describeRuns(runs []wordRun) string {
+	var spans []string
+	for i := 0; i < len(runs); {
+		format, text := runs[i].format(), runs[i].Text
+		for i++; i < len(runs) && runs[i].format() == format; i++ {
+			text += runs[i].Text
+		}
+		if format != "" {
+			text = format + ":" + text
+		}
+		spans = append(spans, text)
+	}
+	return strings.Join(spans, " + ")
+}
+
+// wordTable is a table of the main document part
+type wordTable struct {
+	Columns []struct {
+		Width string `xml:"w,attr"`
+	} `xml:"tblGrid>gridCol"`
+	Rows []struct {
+		Header *struct{} `xml:"trPr>tblHeader"`
+		Cells  []struct {
+			Align struct {
+				Val string `xml:"val,attr"`
+			} `xml:"p>pPr>jc"`
+			Runs []wordRun `xml:"p>r"`
+		} `xml:"tc"`
+	} `xml:"tr"`
+}
+
+func TestTables(t *testing.T) {
+	markdown := "| Left | Center | Right | Plain |\n" +
+		"|:-----|:------:|------:|-------|\n" +
+		"| **bold** and *it* | `code` | ~~gone~~ | a \\| b |\n" +
+		"| short |\n"
+
+	parts := convertParts(t, markdown, Options{})
+	var doc struct {
+		Tables []wordTable `xml:"body>tbl"`
+	}
+	if err := xml.Unmarshal([]byte(parts["word/document.xml"]), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(parts["word/document.xml"], `<w:tblStyle w:val="TableGrid"/>`) || !strings.Contains(parts["word/styles.xml"], `w:styleId="TableGrid"`) {
+		t.Error("table style missing")
+	}
+	if len(doc.Tables) != 1 {
+		t.Fatalf("got %d tables, want 1", len(doc.Tables))
+	}
+	table := doc.Tables[0]
+	if len(table.Columns) != 4 || len(table.Rows) != 3 {
+		t.Fatalf("got %d columns and %d rows, want 4 and 3", len(table.Columns), len(table.Rows))
+	}
+
+	var got [][]string
+	for i, row := range table.Rows {
+		if (row.Header != nil) != (i == 0) {
+			t.Errorf("row %d: header %v", i, row.Header != nil)
+		}
+		if len(row.Cells) != len(table.Columns) {
+			t.Errorf("row %d: got %d cells", i, len(row.Cells))
+		}
+		var cells []string
+		for j, cell := range row.Cells {
+			if want := []string{"left", "center", "right", ""}[j]; cell.Align.Val != want {
+				t.Errorf("row %d cell %d: aligned %q, want %q", i, j, cell.Align.Val, want)
+			}
+			cells = append(cells, describeRuns(cell.Runs))
+		}
+		got = append(got, cells)
+	}
+
+	want := [][]string{
+		{"b:Left", "b:Center", "b:Right", "b:Plain"},
+		{"b:bold +  and  + i:it", "VerbatimChar:code", "strike:gone", "a | b"},
+		{"short", "", "", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got cells\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/shared/frontmatter"
+)
+
+// Package-level relationships, content types and part names of the
+// document properties
+const (
+	relTypeCoreProps = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	relTypeAppProps  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties"
+
+	contentTypeCoreProps = "application/vnd.openxmlformats-package.core-properties+xml"
+	contentTypeAppProps  = "application/vnd.openxmlformats-officedocument.extended-properties+xml"
+
+	corePropsPart = "docProps/core.xml"
+	appPropsPart  = "docProps/app.xml"
+)
+
+// corePropsXML creates docProps/core.xml from the front matter. The created
+// date is the front matter date, or the time of conversion.
+func (c *Converter) corePropsXML() string {
+	meta := c.meta
+	if meta == nil {
+		meta = &FrontMatter{}
+	}
+
+	now := time.Now().UTC()
+	created := now
+	if date, ok := frontmatter.ParseDate(meta.Date); ok {
+		created = date.UTC()
+	} else if meta.Date != "" {
+		c.warn("unrecognized front matter date %q", meta.Date)
+	}
+
+	var props strings.Builder
+	element := func(name, value string) {
+		if value != "" {
+			props.WriteString(fmt.Sprintf("\n  <%s>%s</%s>", name, escapeXML(value), name))
+		}
+	}
+	element("dc:title", meta.Title)
+	element("dc:subject", meta.Subject)
+	element("dc:creator", strings.Join(meta.Author, "; "))
+	element("cp:keywords", strings.Join(meta.Keywords, ", "))
+	element("dc:description", meta.Description)
+	element("dc:language", meta.Lang)
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:dcmitype="http://purl.org/dc/dcmitype/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">%s
+  <dcterms:created xsi:type="dcterms:W3CDTF">%s</dcterms:created>
+  <dcterms:modified xsi:type="dcterms:W3CDTF">%s</dcterms:modified>
+</cp:coreProperties>`, props.String(), created.Format(time.RFC3339), now.Format(time.RFC3339))
+}
+
+// appPropsXML creates docProps/app.xml
+func (c *Converter) appPropsXML() string {
+	company := ""
+	if c.meta != nil && c.meta.Company != "" {
+		company = fmt.Sprintf("\n  <Company>%s</Company>", escapeXML(c.meta.Company))
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes">
+  <Application>markdown2word</Application>%s
+</Properties>`, company)
+}
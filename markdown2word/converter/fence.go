@@ -0,0 +1,232 @@
+package converter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// FenceRenderer turns the content of a fenced code block into a PNG, JPEG
+// or GIF image, which replaces the code in the document
+type FenceRenderer interface {
+	Render(source []byte) ([]byte, error)
+}
+
+// FenceRendererFunc adapts an ordinary function to a FenceRenderer
+type FenceRendererFunc func(source []byte) ([]byte, error)
+
+// Render implements FenceRenderer
+func (f FenceRendererFunc) Render(source []byte) ([]byte, error) {
+	return f(source)
+}
+
+// CommandRenderer is a FenceRenderer running a local command line, which
+// reads the block on standard input and writes the image to standard
+// output. The command line is split at spaces; quote words containing
+// spaces with single or double quotes, or put a backslash before a space or
+// quote. Other backslashes are kept, so Windows paths need no escaping. No
+// shell is involved.
+type CommandRenderer string
+
+// Render implements FenceRenderer
+func (r CommandRenderer) Render(source []byte) ([]byte, error) {
+	args, err := splitCommand(string(r))
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(source)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %v: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", args[0], err)
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("%s produced no image", args[0])
+	}
+	return stdout.Bytes(), nil
+}
+
+// DefaultFenceCommands are the commands of common diagram tools, used when a
+// fence language is enabled without a command
+var DefaultFenceCommands = map[string]string{
+	"dot":      "dot -Tpng",
+	"mermaid":  "mmdc --input - --output - --outputFormat png",
+	"plantuml": "plantuml -tpng -pipe",
+}
+
+// splitCommand splits a command line into words at unquoted spaces
+func splitCommand(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord, escaped := false, false
+	for _, r := range command {
+		if escaped {
+			escaped = false
+			if r == '\'' || r == '"' || (quote == 0 && unicode.IsSpace(r)) {
+				word.WriteRune(r)
+				continue
+			}
+			word.WriteRune('\\')
+		}
+		switch {
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if escaped {
+		word.WriteRune('\\')
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command %q", command)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// fenceRenderer returns the renderer registered for a fence language, if any
+func (c *Converter) fenceRenderer(language string) FenceRenderer {
+	if language == "" {
+		return nil
+	}
+	if r, ok := c.opts.FenceRenderers[language]; ok {
+		return r
+	}
+	for name, r := range c.opts.FenceRenderers {
+		if strings.EqualFold(name, language) {
+			return r
+		}
+	}
+	return nil
+}
+
+// addFenceImage renders a fenced block with its renderer and adds the image
+// as a paragraph of its own, with the source as alternative text. It
+// reports false, after a warning, when the block should stay code.
+func (c *Converter) addFenceImage(language string, r FenceRenderer, code string) bool {
+	key := fenceKey(language, r, code)
+	idx, ok := c.mediaIndex[key]
+	if !ok {
+		data, err := c.renderFence(key, r, code)
+		if err == nil {
+			idx, err = c.addMedia(key, data, language+" block")
+		}
+		if err != nil {
+			c.warn("%s block kept as code: %v", language, err)
+			return false
+		}
+	}
+
+	c.paragraphs = append(c.paragraphs, fmt.Sprintf(`<w:p>
+      <w:pPr>
+        <w:pStyle w:val="BodyText"/>
+        <w:jc w:val="center"/>
+      </w:pPr>
+      %s
+    </w:p>`, c.wrapRuns([]RunStyle{{Drawing: c.inlineDrawing(idx, code)}})))
+	return true
+}
+
+// renderFence returns the image of a fenced block, from the cache when the
+// same block was rendered before. Images of command renderers are also
+// cached on disk in Options.FenceCacheDir.
+func (c *Converter) renderFence(key string, r FenceRenderer, code string) ([]byte, error) {
+	if data, ok := c.fenceImages[key]; ok {
+		return data, nil
+	}
+	if c.fenceImages == nil {
+		c.fenceImages = map[string][]byte{}
+	}
+
+	var path string
+	if _, ok := r.(CommandRenderer); ok && c.opts.FenceCacheDir != "" {
+		path = filepath.Join(c.opts.FenceCacheDir, strings.TrimPrefix(key, "fence:"))
+		if data, err := os.ReadFile(path); err == nil {
+			c.fenceImages[key] = data
+			return data, nil
+		}
+	}
+
+	data, err := r.Render([]byte(code + "\n"))
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("renderer output is not a PNG, JPEG or GIF image")
+	}
+	c.fenceImages[key] = data
+
+	if path != "" {
+		if err := writeCacheFile(path, data); err != nil {
+			c.warn("failed to cache rendered block: %v", err)
+		}
+	}
+	return data, nil
+}
+
+// fenceKey identifies the image of a fenced block by a hash of its language,
+// command and content
+func fenceKey(language string, r FenceRenderer, code string) string {
+	command, _ := r.(CommandRenderer)
+	sum := sha256.Sum256([]byte(strings.ToLower(language) + "\x00" + string(command) + "\x00" + code))
+	return "fence:" + hex.EncodeToString(sum[:])
+}
+
+// writeCacheFile writes data through a temporary file, so that parallel
+// conversions never read a partial file
+func writeCacheFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
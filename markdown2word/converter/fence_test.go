@@ -0,0 +1,152 @@
+package converter
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{"dot -Tpng", []string{"dot", "-Tpng"}, false},
+		{"  dot \t -Tpng  ", []string{"dot", "-Tpng"}, false},
+		{"", nil, false},
+		{`mmdc --config "my config.json"`, []string{"mmdc", "--config", "my config.json"}, false},
+		{`render 'it''s'`, []string{"render", "its"}, false},
+		{`say "it's"`, []string{"say", "it's"}, false},
+		{`draw --title=""`, []string{"draw", "--title="}, false},
+		{`draw ""`, []string{"draw", ""}, false},
+		{`/opt/My\ Tools/dot -Tpng`, []string{"/opt/My Tools/dot", "-Tpng"}, false},
+		{`echo \"quoted\"`, []string{"echo", `"quoted"`}, false},
+		{`echo "a \"b\" c"`, []string{"echo", `a "b" c`}, false},
+		{`echo 'a\ b'`, []string{"echo", `a\ b`}, false},
+		{`C:\Tools\dot.exe -Tpng`, []string{`C:\Tools\dot.exe`, "-Tpng"}, false},
+		{`\\server\share\dot`, []string{`\\server\share\dot`}, false},
+		{`dot trailing\`, []string{"dot", `trailing\`}, false},
+		{`mmdc --config "my config.json`, nil, true},
+		{`render 'open`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.command)
+		switch {
+		case tt.wantErr:
+			if err == nil {
+				t.Errorf("%s: got %q, want an error", tt.command, got)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.command, err)
+		case !reflect.DeepEqual(got, tt.want):
+			t.Errorf("%s: got %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+// fixtureRenderer returns a renderer printing a copy of the fixture image,
+// whatever the block, and the path of the copy
+func fixtureRenderer(t *testing.T) (CommandRenderer, string) {
+	t.Helper()
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not found")
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "fence", "diagram.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "my diagram.png")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return CommandRenderer("cat '" + path + "'"), path
+}
+
+func TestFenceRenderer(t *testing.T) {
+	r, path := fixtureRenderer(t)
+	cacheDir := t.TempDir()
+	opts := Options{
+		FenceRenderers: map[string]FenceRenderer{"dot": r},
+		FenceCacheDir:  cacheDir,
+	}
+	markdown := "```dot\ndigraph { a -> b }\n```\n\n```DOT\ndigraph { a -> b }\n```\n\n```go\nfunc main() {}\n```\n"
+
+	parts, warnings := convertWithWarnings(t, markdown, opts)
+	if len(warnings) != 0 {
+		t.Fatalf("got warnings %q", warnings)
+	}
+	doc := parts["word/document.xml"]
+	if n := strings.Count(doc, "<w:drawing>"); n != 2 {
+		t.Errorf("got %d drawings, want 2", n)
+	}
+	if strings.Contains(doc, "a -&gt; b</w:t>") {
+		t.Error("rendered block kept as code")
+	}
+	if !strings.Contains(doc, `descr="digraph { a -&gt; b }"`) {
+		t.Error("block source not the alternative text")
+	}
+	if !strings.Contains(doc, "main") {
+		t.Error("block without a renderer missing")
+	}
+
+	// The same block is packaged once
+	var media []string
+	for name := range parts {
+		if strings.HasPrefix(name, "word/media/") {
+			media = append(media, name)
+		}
+	}
+	if len(media) != 1 {
+		t.Errorf("got media %q, want one image", media)
+	}
+	cached, err := os.ReadDir(cacheDir)
+	if err != nil || len(cached) != 1 {
+		t.Fatalf("got cache %v, %v; want one file", cached, err)
+	}
+
+	// Later conversions take the image from the cache instead of running
+	// the command, which would now fail
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	parts, warnings = convertWithWarnings(t, markdown, opts)
+	if len(warnings) != 0 {
+		t.Errorf("cached: got warnings %q", warnings)
+	}
+	if n := strings.Count(parts["word/document.xml"], "<w:drawing>"); n != 2 {
+		t.Errorf("cached: got %d drawings, want 2", n)
+	}
+}
+
+func TestFenceRendererFailure(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	tests := []struct {
+		name    string
+		command string
+		warning string
+	}{
+		{"failing command", `sh -c "echo syntax error >&2; exit 1"`, "dot block kept as code: sh: exit status 1: syntax error"},
+		{"not an image", `sh -c "echo digraph"`, "dot block kept as code: renderer output is not a PNG, JPEG or GIF image"},
+		{"no output", "sh -c true", "dot block kept as code: sh produced no image"},
+		{"missing command", "no-such-renderer -Tpng", "dot block kept as code: no-such-renderer: "},
+		{"unterminated quote", `sh -c "exit`, "dot block kept as code: unterminated quote"},
+	}
+	for _, tt := range tests {
+		parts, warnings := convertWithWarnings(t, "```dot\ndigraph\n```\n", Options{
+			FenceRenderers: map[string]FenceRenderer{"dot": CommandRenderer(tt.command)},
+		})
+		if len(warnings) != 1 || !strings.HasPrefix(warnings[0], tt.warning) {
+			t.Errorf("%s: got warnings %q, want %q", tt.name, warnings, tt.warning)
+		}
+		doc := parts["word/document.xml"]
+		if strings.Contains(doc, "<w:drawing>") || !strings.Contains(doc, `<w:pStyle w:val="SourceCode"/>`) || !strings.Contains(doc, "digraph") {
+			t.Errorf("%s: block not kept as code", tt.name)
+		}
+	}
+}
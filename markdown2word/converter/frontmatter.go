@@ -0,0 +1,115 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/example/shared/frontmatter"
+)
+
+// FrontMatter is the YAML block at the top of a Markdown document, delimited
+// by "---" lines. Besides document properties it can override Options for
+// the document; unset overrides keep the converter's options.
+type FrontMatter struct {
+	frontmatter.Metadata `yaml:",inline"`
+
+	Description string `yaml:"description"`
+	Company     string `yaml:"company"`
+
+	// Start the document with a title page
+	TitlePage *bool `yaml:"title-page"`
+
+	// Option overrides
+	PageSize          *string  `yaml:"page-size"`
+	FontFamily        *string  `yaml:"font-family"`
+	FontSize          *float64 `yaml:"font-size"`
+	CodeFontFamily    *string  `yaml:"code-font-family"`
+	CodeFontSize      *float64 `yaml:"code-font-size"`
+	EastAsianFont     *string  `yaml:"east-asian-font"`
+	ComplexScriptFont *string  `yaml:"complex-script-font"`
+	Margin            *float64 `yaml:"margin"`
+	MarginTop         *float64 `yaml:"margin-top"`
+	MarginBottom      *float64 `yaml:"margin-bottom"`
+	MarginLeft        *float64 `yaml:"margin-left"`
+	MarginRight       *float64 `yaml:"margin-right"`
+	CodeStyle         *string  `yaml:"code-style"`
+	LineNumbers       *bool    `yaml:"line-numbers"`
+	Endnotes          *bool    `yaml:"endnotes"`
+}
+
+// parseFrontMatter splits a leading front matter block off markdown. A
+// document without front matter is returned as is, with nil front matter.
+func parseFrontMatter(markdown []byte) (*FrontMatter, []byte, error) {
+	fm := &FrontMatter{}
+	found, body, err := frontmatter.Parse(markdown, fm)
+	if err != nil || !found {
+		return nil, body, err
+	}
+	return fm, body, nil
+}
+
+// applyFrontMatter overrides the converter's options with those set in the
+// front matter
+func (c *Converter) applyFrontMatter(fm *FrontMatter) {
+	setString(&c.opts.PageSize, fm.PageSize)
+	setString(&c.opts.FontFamily, fm.FontFamily)
+	setFloat(&c.opts.FontSize, fm.FontSize)
+	setString(&c.opts.CodeFontFamily, fm.CodeFontFamily)
+	setFloat(&c.opts.CodeFontSize, fm.CodeFontSize)
+	setString(&c.opts.EastAsianFont, fm.EastAsianFont)
+	setString(&c.opts.ComplexScriptFont, fm.ComplexScriptFont)
+	if fm.Margin != nil {
+		c.opts.MarginTop = *fm.Margin
+		c.opts.MarginBottom = *fm.Margin
+		c.opts.MarginLeft = *fm.Margin
+		c.opts.MarginRight = *fm.Margin
+	}
+	setFloat(&c.opts.MarginTop, fm.MarginTop)
+	setFloat(&c.opts.MarginBottom, fm.MarginBottom)
+	setFloat(&c.opts.MarginLeft, fm.MarginLeft)
+	setFloat(&c.opts.MarginRight, fm.MarginRight)
+	setString(&c.opts.CodeStyle, fm.CodeStyle)
+	setBool(&c.opts.LineNumbers, fm.LineNumbers)
+	setBool(&c.opts.TitlePage, fm.TitlePage)
+	setBool(&c.opts.Endnotes, fm.Endnotes)
+}
+
+func setString(dst *string, src *string) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setBool(dst *bool, src *bool) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setFloat(dst *float64, src *float64) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// addTitlePage adds the title, subject, authors and date followed by a page
+// break. Each line uses its own named style so templates can restyle it.
+func (c *Converter) addTitlePage() {
+	add := func(style, text string) {
+		if text == "" {
+			return
+		}
+		c.paragraphs = append(c.paragraphs, fmt.Sprintf(`<w:p>
+      <w:pPr><w:pStyle w:val="%s"/></w:pPr>
+      <w:r><w:t xml:space="preserve">%s</w:t></w:r>
+    </w:p>`, style, escapeXML(text)))
+	}
+
+	add("Title", c.meta.Title)
+	add("Subtitle", c.meta.Subject)
+	for _, author := range c.meta.Author {
+		add("Author", author)
+	}
+	add("Date", c.meta.Date)
+
+	c.paragraphs = append(c.paragraphs, `<w:p><w:r><w:br w:type="page"/></w:r></w:p>`)
+}
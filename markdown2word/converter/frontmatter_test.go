@@ -0,0 +1,61 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/example/shared/frontmatter"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	fm, body, err := parseFrontMatter([]byte("---\ntitle: Report\nauthor: [Ada, Grace]\ncompany: Acme\ntitle-page: true\nfont-size: 12\nendnotes: true\n---\nBody\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm.Title != "Report" || !reflect.DeepEqual(fm.Author, frontmatter.StringList{"Ada", "Grace"}) || fm.Company != "Acme" {
+		t.Errorf("got metadata %+v", fm)
+	}
+	if fm.TitlePage == nil || !*fm.TitlePage || fm.FontSize == nil || *fm.FontSize != 12 || fm.Endnotes == nil || !*fm.Endnotes {
+		t.Errorf("got overrides %+v", fm)
+	}
+	if string(body) != "\n\n\n\n\n\n\n\nBody\n" {
+		t.Errorf("got body %q", body)
+	}
+
+	// Thematic breaks around text aren't front matter
+	markdown := "---\nSome text\n---\n"
+	if fm, body, err := parseFrontMatter([]byte(markdown)); fm != nil || string(body) != markdown || err != nil {
+		t.Errorf("thematic breaks: got %+v, %q, %v", fm, body, err)
+	}
+}
+
+func TestParseFrontMatterRejectsBadFields(t *testing.T) {
+	_, _, err := parseFrontMatter([]byte("---\nfont-size: large\n---\nBody\n"))
+	if err == nil || !strings.Contains(err.Error(), "invalid front matter") {
+		t.Errorf("got %v, want an invalid front matter error", err)
+	}
+}
+
+func TestApplyFrontMatter(t *testing.T) {
+	fm, _, err := parseFrontMatter([]byte("---\npage-size: A4\nfont-family: Georgia\nmargin: 0.5\nmargin-top: 2\nline-numbers: true\n---\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(Options{PageSize: "Letter", FontFamily: "Calibri", FontSize: 11, MarginLeft: 1})
+	c.applyFrontMatter(fm)
+	want := Options{
+		PageSize:     "A4",
+		FontFamily:   "Georgia",
+		FontSize:     11,
+		MarginTop:    2,
+		MarginBottom: 0.5,
+		MarginLeft:   0.5,
+		MarginRight:  0.5,
+		LineNumbers:  true,
+	}
+	if !reflect.DeepEqual(c.opts, want) {
+		t.Errorf("got options %+v\nwant %+v", c.opts, want)
+	}
+}
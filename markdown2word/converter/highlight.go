@@ -0,0 +1,100 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// DefaultCodeStyle is the chroma style used when Options.CodeStyle is empty.
+// It matches the style used by markdown2pdf.
+const DefaultCodeStyle = "github"
+
+// Color used for line numbers in code blocks
+const lineNumberColor = "A0A0A0"
+
+// highlightCode tokenizes code with the lexer for language and returns one
+// slice of colored runs per line. Unknown or empty languages produce plain
+// runs.
+func (c *Converter) highlightCode(language, code string) [][]RunStyle {
+	lexer := lexers.Get(language)
+	if language == "" || lexer == nil {
+		var lines [][]RunStyle
+		for _, line := range strings.Split(code, "\n") {
+			lines = append(lines, []RunStyle{{Text: line}})
+		}
+		return lines
+	}
+
+	style := c.codeStyle()
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		c.warn("failed to highlight %s code: %v", language, err)
+		return c.highlightCode("", code)
+	}
+
+	var lines [][]RunStyle
+	for _, tokens := range chroma.SplitTokensIntoLines(iterator.Tokens()) {
+		var runs []RunStyle
+		for _, token := range tokens {
+			text := strings.TrimSuffix(token.Value, "\n")
+			if text == "" {
+				continue
+			}
+
+			entry := style.Get(token.Type)
+			run := RunStyle{
+				Text:   text,
+				Bold:   entry.Bold == chroma.Yes,
+				Italic: entry.Italic == chroma.Yes,
+			}
+			if entry.Colour.IsSet() {
+				run.Color = strings.ToUpper(strings.TrimPrefix(entry.Colour.String(), "#"))
+			}
+			runs = append(runs, run)
+		}
+		lines = append(lines, runs)
+	}
+	return lines
+}
+
+// codeStyle returns the configured chroma style, warning once about
+// unknown names
+func (c *Converter) codeStyle() *chroma.Style {
+	name := c.opts.CodeStyle
+	if name == "" {
+		name = DefaultCodeStyle
+	}
+	style, ok := styles.Registry[strings.ToLower(name)]
+	if !ok {
+		if !c.unknownStyleWarned {
+			c.warn("unknown code style %q, using %q", name, DefaultCodeStyle)
+			c.unknownStyleWarned = true
+		}
+		style = styles.Get(DefaultCodeStyle)
+	}
+	return style
+}
+
+// codeBackground returns the background fill of the code style, or "" when
+// the style's background is white and the SourceCode shading should be kept
+func (c *Converter) codeBackground() string {
+	bg := c.codeStyle().Get(chroma.Background).Background
+	if !bg.IsSet() {
+		return ""
+	}
+	fill := strings.ToUpper(strings.TrimPrefix(bg.String(), "#"))
+	if fill == "FFFFFF" {
+		return ""
+	}
+	return fill
+}
+
+// lineNumberRun returns the gutter run for a code line
+func lineNumberRun(n, total int) RunStyle {
+	width := len(fmt.Sprint(total))
+	return RunStyle{Text: fmt.Sprintf("%*d  ", width, n), Color: lineNumberColor}
+}
@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// codeLines returns the SourceCode paragraphs of a document
+func codeLines(document string) []string {
+	return regexp.MustCompile(`(?s)<w:p>\s*<w:pPr>\s*<w:pStyle w:val="SourceCode"/>.*?</w:p>`).FindAllString(document, -1)
+}
+
+// tokenColor returns the color a chroma style gives a token type, as used
+// in w:color
+func tokenColor(style string, token chroma.TokenType) string {
+	return strings.ToUpper(strings.TrimPrefix(styles.Get(style).Get(token).Colour.String(), "#"))
+}
+
+func TestHighlightedCode(t *testing.T) {
+	markdown := "```go\nfunc main() {\n\treturn\n}\n```\n"
+
+	parts, warnings := convertWithWarnings(t, markdown, Options{})
+	if len(warnings) != 0 {
+		t.Errorf("got warnings %q", warnings)
+	}
+	lines := codeLines(parts["word/document.xml"])
+	if len(lines) != 3 {
+		t.Fatalf("got %d code lines, want 3", len(lines))
+	}
+	keyword := `<w:color w:val="` + tokenColor(DefaultCodeStyle, chroma.KeywordDeclaration) + `"/>`
+	if !strings.Contains(lines[0], keyword+`</w:rPr><w:t xml:space="preserve">func</w:t>`) {
+		t.Errorf("func not colored as a keyword: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `>return</w:t>`) || !strings.Contains(lines[1], "<w:color ") {
+		t.Errorf("second line not highlighted: %s", lines[1])
+	}
+
+	// Another style colors the same code differently and shades the block
+	// with its background
+	parts, _ = convertWithWarnings(t, markdown, Options{CodeStyle: "monokai"})
+	lines = codeLines(parts["word/document.xml"])
+	monokai := tokenColor("monokai", chroma.KeywordDeclaration)
+	background := strings.ToUpper(strings.TrimPrefix(styles.Get("monokai").Get(chroma.Background).Background.String(), "#"))
+	if !strings.Contains(lines[0], `<w:color w:val="`+monokai+`"/>`) || !strings.Contains(lines[0], `<w:shd w:val="clear" w:color="auto" w:fill="`+background+`"/>`) {
+		t.Errorf("monokai style not applied: %s", lines[0])
+	}
+}
+
+func TestHighlightFallbacks(t *testing.T) {
+	markdown := "```nosuchlanguage\nfunc main() {}\n```\n\n```\nplain\n```\n"
+
+	parts, warnings := convertWithWarnings(t, markdown, Options{})
+	if len(warnings) != 0 {
+		t.Errorf("got warnings %q", warnings)
+	}
+	lines := codeLines(parts["word/document.xml"])
+	if len(lines) != 2 {
+		t.Fatalf("got %d code lines, want 2", len(lines))
+	}
+	for _, line := range lines {
+		if strings.Contains(line, "<w:color ") {
+			t.Errorf("code without a lexer colored: %s", line)
+		}
+	}
+	if !strings.Contains(lines[0], `<w:t xml:space="preserve">func main() {}</w:t>`) {
+		t.Errorf("code without a lexer not kept whole: %s", lines[0])
+	}
+
+	// An unknown style warns once and highlights with the default style
+	markdown = "```go\nfunc a() {}\n```\n\n```go\nfunc b() {}\n```\n"
+	parts, warnings = convertWithWarnings(t, markdown, Options{CodeStyle: "nosuchstyle"})
+	want := []string{`unknown code style "nosuchstyle", using "github"`}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("got warnings %q, want %q", warnings, want)
+	}
+	defaults := convertParts(t, markdown, Options{})
+	if parts["word/document.xml"] != defaults["word/document.xml"] {
+		t.Error("unknown style not replaced by the default style")
+	}
+}
+
+func TestCodeLineNumbers(t *testing.T) {
+	var code strings.Builder
+	for i := 0; i < 10; i++ {
+		code.WriteString("x\n")
+	}
+	parts := convertParts(t, "```\n"+code.String()+"```\n", Options{LineNumbers: true})
+	lines := codeLines(parts["word/document.xml"])
+	if len(lines) != 10 {
+		t.Fatalf("got %d code lines, want 10", len(lines))
+	}
+	gutter := `<w:color w:val="` + lineNumberColor + `"/></w:rPr><w:t xml:space="preserve">`
+	if !strings.Contains(lines[0], gutter+" 1  </w:t>") || !strings.Contains(lines[9], gutter+"10  </w:t>") {
+		t.Errorf("line numbers not padded to the widest: %s\n%s", lines[0], lines[9])
+	}
+}
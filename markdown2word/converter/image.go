@@ -0,0 +1,211 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"  // Register GIF decoder
+	_ "image/jpeg" // Register JPEG decoder
+	_ "image/png"  // Register PNG decoder
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EMUs (English Metric Units) used by DrawingML
+const (
+	emuPerPixel = 9525 // At 96 DPI
+	emuPerTwip  = 635
+)
+
+// mediaFile is an image packaged under word/media/
+type mediaFile struct {
+	Name        string
+	Ext         string
+	ContentType string
+	Data        []byte
+	RelID       string
+	Width       int // In pixels
+	Height      int // In pixels
+}
+
+// Content types for supported image formats
+var imageContentTypes = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+}
+
+// embedImage packages the image referenced by dest and returns the inline
+// drawing XML that displays it
+func (c *Converter) embedImage(dest, altText string) (string, error) {
+	idx, ok := c.mediaIndex[dest]
+	if !ok {
+		data, err := c.loadImage(dest)
+		if err != nil {
+			return "", err
+		}
+		if idx, err = c.addMedia(dest, data, shortDest(dest)); err != nil {
+			return "", err
+		}
+	}
+	return c.inlineDrawing(idx, altText), nil
+}
+
+// addMedia packages image data under word/media/ and indexes it by key.
+// label names the image in errors.
+func (c *Converter) addMedia(key string, data []byte, label string) (int, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("unsupported image format: %s", label)
+	}
+	contentType, ok := imageContentTypes[format]
+	if !ok {
+		return 0, fmt.Errorf("unsupported image format %q: %s", format, label)
+	}
+
+	ext := format
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+	name := c.mediaName(ext)
+
+	c.media = append(c.media, mediaFile{
+		Name:        name,
+		Ext:         ext,
+		ContentType: contentType,
+		Data:        data,
+		RelID:       c.addRelationship(relTypeImage, "media/"+name, false),
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+	})
+	c.mediaIndex[key] = len(c.media) - 1
+	return len(c.media) - 1, nil
+}
+
+// inlineDrawing returns the drawing XML displaying a packaged image
+func (c *Converter) inlineDrawing(idx int, altText string) string {
+	m := c.media[idx]
+	cx, cy := c.imageExtent(m.Width, m.Height)
+	c.drawings++
+	return drawingXML(c.drawings, m.Name, m.RelID, altText, cx, cy)
+}
+
+// mediaName returns an unused file name under word/media/
+func (c *Converter) mediaName(ext string) string {
+	used := map[string]bool{}
+	for _, m := range c.media {
+		used[m.Name] = true
+	}
+
+	for n := len(c.media) + 1; ; n++ {
+		name := fmt.Sprintf("image%d.%s", n, ext)
+		if used[name] || (c.reference != nil && c.reference.has("word/media/"+name)) {
+			continue
+		}
+		return name
+	}
+}
+
+// loadImage reads image bytes from a data URI or a local file path
+func (c *Converter) loadImage(dest string) ([]byte, error) {
+	if strings.HasPrefix(dest, "data:") {
+		return decodeDataURI(dest)
+	}
+
+	if u, err := url.Parse(dest); err == nil && u.Scheme != "" && u.Scheme != "file" && len(u.Scheme) > 1 {
+		return nil, fmt.Errorf("remote images are not embedded: %s", dest)
+	}
+
+	path := strings.TrimPrefix(dest, "file://")
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+	if !filepath.IsAbs(path) && c.baseDir != "" {
+		path = filepath.Join(c.baseDir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("image not found: %s", dest)
+	}
+	return data, nil
+}
+
+// decodeDataURI decodes the payload of a data: URI
+func decodeDataURI(uri string) ([]byte, error) {
+	comma := strings.Index(uri, ",")
+	if comma < 0 {
+		return nil, fmt.Errorf("malformed data URI image")
+	}
+
+	meta, payload := uri[len("data:"):comma], uri[comma+1:]
+	if strings.HasSuffix(meta, ";base64") {
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed base64 data URI image: %w", err)
+		}
+		return data, nil
+	}
+
+	data, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, fmt.Errorf("malformed data URI image: %w", err)
+	}
+	return []byte(data), nil
+}
+
+// imageExtent returns the display size in EMUs, scaled down to fit between
+// the page margins
+func (c *Converter) imageExtent(width, height int) (int, int) {
+	cx := width * emuPerPixel
+	cy := height * emuPerPixel
+
+	maxWidth := c.contentWidth() * emuPerTwip
+	if maxWidth > 0 && cx > maxWidth {
+		cy = int(float64(cy) * float64(maxWidth) / float64(cx))
+		cx = maxWidth
+	}
+	return cx, cy
+}
+
+// drawingXML creates an inline <w:drawing> element referencing an embedded image
+func drawingXML(id int, name, relID, altText string, cx, cy int) string {
+	return fmt.Sprintf(`<w:drawing>
+        <wp:inline distT="0" distB="0" distL="0" distR="0">
+          <wp:extent cx="%d" cy="%d"/>
+          <wp:docPr id="%d" name="Picture %d" descr="%s"/>
+          <wp:cNvGraphicFramePr>
+            <a:graphicFrameLocks noChangeAspect="1"/>
+          </wp:cNvGraphicFramePr>
+          <a:graphic>
+            <a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">
+              <pic:pic>
+                <pic:nvPicPr>
+                  <pic:cNvPr id="%d" name="%s"/>
+                  <pic:cNvPicPr/>
+                </pic:nvPicPr>
+                <pic:blipFill>
+                  <a:blip r:embed="%s"/>
+                  <a:stretch><a:fillRect/></a:stretch>
+                </pic:blipFill>
+                <pic:spPr>
+                  <a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm>
+                  <a:prstGeom prst="rect"><a:avLst/></a:prstGeom>
+                </pic:spPr>
+              </pic:pic>
+            </a:graphicData>
+          </a:graphic>
+        </wp:inline>
+      </w:drawing>`, cx, cy, id, id, escapeXML(altText), id, name, relID, cx, cy)
+}
+
+// shortDest truncates long image references such as data URIs for messages
+func shortDest(dest string) string {
+	if len(dest) > 64 {
+		return dest[:64] + "..."
+	}
+	return dest
+}
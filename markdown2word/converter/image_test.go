@@ -0,0 +1,133 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// relationshipTargets returns the targets of a relationships part by ID,
+// failing on IDs used twice
+func relationshipTargets(t *testing.T, part string) map[string]string {
+	t.Helper()
+	targets := map[string]string{}
+	for _, m := range regexp.MustCompile(`<Relationship Id="([^"]+)" Type="[^"]+" Target="([^"]+)"`).FindAllStringSubmatch(part, -1) {
+		if _, ok := targets[m[1]]; ok {
+			t.Errorf("relationship %s used twice", m[1])
+		}
+		targets[m[1]] = m[2]
+	}
+	return targets
+}
+
+func TestImages(t *testing.T) {
+	dir := t.TempDir()
+	pic, other, wide := pngImage(t, 40, 20), pngImage(t, 8, 8), pngImage(t, 2000, 100)
+	var photo bytes.Buffer
+	if err := jpeg.Encode(&photo, image.NewGray(image.Rect(0, 0, 16, 16)), nil); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{"pic.png": pic, "sub/pic.png": other, "my photo.jpg": photo.Bytes(), "wide.png": wide}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inline := pngImage(t, 2, 2)
+	dataURI := "data:image/png;base64," + base64.StdEncoding.EncodeToString(inline)
+	markdown := "![Local](pic.png) ![Again](pic.png)\n\n" +
+		"![Same name](sub/pic.png) ![Photo](my%20photo.jpg)\n\n" +
+		"![Inline](" + dataURI + ") ![Wide](wide.png)\n\n" +
+		"![Missing](missing.png) ![Remote](https://example.com/logo.png)\n"
+	input := filepath.Join(dir, "doc.md")
+	if err := os.WriteFile(input, []byte(markdown), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(Options{FontSize: 11, CodeFontSize: 10})
+	var buf bytes.Buffer
+	if err := c.ConvertFileTo(input, &buf); err != nil {
+		t.Fatal(err)
+	}
+	parts := unzipParts(t, buf.Bytes())
+	doc := parts["word/document.xml"]
+
+	wantWarnings := []string{"image not found: missing.png", "remote images are not embedded: https://example.com/logo.png"}
+	if !reflect.DeepEqual(c.Warnings(), wantWarnings) {
+		t.Errorf("got warnings %q, want %q", c.Warnings(), wantWarnings)
+	}
+	for _, placeholder := range []string{"[Missing]", "[Remote]"} {
+		if !strings.Contains(doc, ">"+placeholder+"</w:t>") {
+			t.Errorf("no placeholder %s", placeholder)
+		}
+	}
+
+	// Every image is packaged once, under a name of its own
+	wantMedia := map[string][]byte{
+		"word/media/image1.png": pic,
+		"word/media/image2.png": other,
+		"word/media/image3.jpg": photo.Bytes(),
+		"word/media/image4.png": inline,
+		"word/media/image5.png": wide,
+	}
+	for name, data := range parts {
+		if !strings.HasPrefix(name, "word/media/") {
+			continue
+		}
+		if want, ok := wantMedia[name]; !ok || !bytes.Equal([]byte(data), want) {
+			t.Errorf("unexpected media %s", name)
+		}
+		delete(wantMedia, name)
+	}
+	for name := range wantMedia {
+		t.Errorf("media %s missing", name)
+	}
+
+	types := parts["[Content_Types].xml"]
+	for _, ext := range []string{`"png" ContentType="image/png"`, `"jpg" ContentType="image/jpeg"`} {
+		if n := strings.Count(types, `<Default Extension=`+ext); n != 1 {
+			t.Errorf("content type %s declared %d times", ext, n)
+		}
+	}
+
+	// Drawings refer to the relationships of their images
+	targets := relationshipTargets(t, parts["word/_rels/document.xml.rels"])
+	var got []string
+	for _, m := range regexp.MustCompile(`descr="([^"]*)"/>(?s:.*?)<a:blip r:embed="([^"]+)"/>`).FindAllStringSubmatch(doc, -1) {
+		got = append(got, m[1]+"="+targets[m[2]])
+	}
+	want := []string{"Local=media/image1.png", "Again=media/image1.png", "Same name=media/image2.png",
+		"Photo=media/image3.jpg", "Inline=media/image4.png", "Wide=media/image5.png"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got drawings %q, want %q", got, want)
+	}
+
+	// Images keep their size at 96 DPI, or shrink to the page width
+	extents := regexp.MustCompile(`<wp:extent cx="(\d+)" cy="(\d+)"/>`).FindAllStringSubmatch(doc, -1)
+	if len(extents) != len(want) {
+		t.Fatalf("got %d extents", len(extents))
+	}
+	if extents[0][1] != strconv.Itoa(40*emuPerPixel) || extents[0][2] != strconv.Itoa(20*emuPerPixel) {
+		t.Errorf("got extent %s x %s for a 40x20 image", extents[0][1], extents[0][2])
+	}
+	maxWidth := c.contentWidth() * emuPerTwip
+	if cx, _ := strconv.Atoi(extents[5][1]); cx != maxWidth {
+		t.Errorf("wide image is %d EMUs wide, want %d", cx, maxWidth)
+	}
+	if cy, _ := strconv.Atoi(extents[5][2]); cy != maxWidth/20 {
+		t.Errorf("wide image is %d EMUs high, want %d", cy, maxWidth/20)
+	}
+}
@@ -0,0 +1,117 @@
+package converter
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// TestInlineRuns checks the runs produced for every Markdown file in
+// testdata/inline against the matching .golden file
+func TestInlineRuns(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "inline", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no test cases found in testdata/inline")
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".md")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := inlineDump(t, source)
+			golden := strings.TrimSuffix(file, ".md") + ".golden"
+
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file (run go test -update): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("runs mismatch for %s\n--- got ---\n%s--- want ---\n%s", file, got, want)
+			}
+		})
+	}
+}
+
+// inlineDump renders the runs of every paragraph and text block in source, one run per line
+func inlineDump(t *testing.T, source []byte) string {
+	t.Helper()
+
+	c := New(Options{FontSize: 11, CodeFontSize: 10})
+	c.mediaIndex = map[string]int{}
+	c.hyperlinks = map[string]string{}
+
+	root := newMarkdown().Parser().Parse(text.NewReader(source))
+	c.collectFootnotes(root)
+
+	var out strings.Builder
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || (n.Kind() != ast.KindParagraph && n.Kind() != ast.KindTextBlock) {
+			return ast.WalkContinue, nil
+		}
+		for _, run := range c.processInlineNodes(n, source) {
+			out.WriteString(formatRun(run))
+			out.WriteString("\n")
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return out.String()
+}
+
+// formatRun describes a run as its active attributes followed by its text
+func formatRun(run RunStyle) string {
+	if run.Break {
+		return "break"
+	}
+	if run.Math != "" {
+		return "math"
+	}
+	if strings.Contains(run.Note, "NOTEREF") {
+		return "note again"
+	}
+	if run.Note != "" {
+		return "note"
+	}
+
+	var attrs []string
+	if run.Bold {
+		attrs = append(attrs, "bold")
+	}
+	if run.Italic {
+		attrs = append(attrs, "italic")
+	}
+	if run.Strike {
+		attrs = append(attrs, "strike")
+	}
+	if run.Code {
+		attrs = append(attrs, "code")
+	}
+	if run.Link {
+		attrs = append(attrs, "link="+run.LinkURL)
+	}
+	if len(attrs) == 0 {
+		attrs = append(attrs, "plain")
+	}
+	return fmt.Sprintf("%s %q", strings.Join(attrs, ","), run.Text)
+}
@@ -0,0 +1,248 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+// noteKind describes footnotes or endnotes. Word stores both the same way,
+// in a part of their own whose elements are named after the kind.
+type noteKind struct {
+	Name        string // "footnote" or "endnote"
+	TextStyle   string
+	RefStyle    string
+	RelType     string
+	ContentType string
+}
+
+var (
+	footnoteKind = noteKind{
+		Name:        "footnote",
+		TextStyle:   "FootnoteText",
+		RefStyle:    "FootnoteReference",
+		RelType:     "http://schemas.openxmlformats.org/officeDocument/2006/relationships/footnotes",
+		ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml",
+	}
+	endnoteKind = noteKind{
+		Name:        "endnote",
+		TextStyle:   "EndnoteText",
+		RefStyle:    "EndnoteReference",
+		RelType:     "http://schemas.openxmlformats.org/officeDocument/2006/relationships/endnotes",
+		ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.endnotes+xml",
+	}
+)
+
+// target returns the part name relative to word/, e.g. footnotes.xml
+func (k noteKind) target() string {
+	return k.Name + "s.xml"
+}
+
+// part returns the package part name, e.g. word/footnotes.xml
+func (k noteKind) part() string {
+	return "word/" + k.target()
+}
+
+// relsPart returns the name of the part's relationships part
+func (k noteKind) relsPart() string {
+	return "word/_rels/" + k.target() + ".rels"
+}
+
+// separatorPattern matches the separator notes of a notes part, which have
+// a w:type attribute unlike the notes referenced from the body
+func (k noteKind) separatorPattern() *regexp.Regexp {
+	return regexp.MustCompile(`(?s)<w:` + k.Name + `\b[^>]*\bw:type="[^"]*"[^>]*>.*?</w:` + k.Name + `>`)
+}
+
+// noteKind returns the kind of note Markdown footnotes become
+func (c *Converter) noteKind() noteKind {
+	if c.opts.Endnotes {
+		return endnoteKind
+	}
+	return footnoteKind
+}
+
+// collectFootnotes indexes the footnote definitions of a document by
+// number, and picks note IDs that follow the separators of a template. Word
+// numbers notes in the order they are referenced, so footnotes get their
+// numbers and bookmarks from the order of their first citation outside
+// footnotes.
+func (c *Converter) collectFootnotes(root ast.Node) {
+	c.footnotes = map[int]*east.Footnote{}
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if footnote, ok := n.(*east.Footnote); ok && entering {
+			c.footnotes[footnote.Index] = footnote
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	used := map[string]bool{}
+	for _, name := range c.bookmarkNames {
+		used[strings.ToLower(name)] = true
+	}
+	c.noteIDs = map[int]int{}
+	c.noteNumbers = map[int]int{}
+	c.noteBookmarks = map[int]string{}
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *east.FootnoteList:
+			return ast.WalkSkipChildren, nil
+		case *east.FootnoteLink:
+			if _, seen := c.noteNumbers[n.Index]; seen || c.footnotes[n.Index] == nil {
+				break
+			}
+			number := len(c.noteNumbers) + 1
+			name := fmt.Sprintf("_Note%d", number)
+			for i := 1; used[strings.ToLower(name)]; i++ {
+				name = fmt.Sprintf("_Note%d_%d", number, i)
+			}
+			used[strings.ToLower(name)] = true
+			c.noteNumbers[n.Index] = number
+			c.noteBookmarks[n.Index] = name
+		}
+		return ast.WalkContinue, nil
+	})
+
+	c.noteHyperlinks = map[string]string{}
+	c.noteMedia = map[string]int{}
+	c.noteIDBase = 0
+	kind := c.noteKind()
+	if c.reference != nil && c.reference.has(kind.part()) {
+		separators := strings.Join(kind.separatorPattern().FindAllString(string(c.reference.parts[kind.part()]), -1), "")
+		c.noteIDBase = maxInt(regexp.MustCompile(`<w:`+kind.Name+`\b[^>]*\bw:id="(\d+)"`), separators)
+	}
+}
+
+// noteReference returns the run referencing the footnote a link points to.
+// The first citation outside footnotes converts the footnote into a Word
+// note. Notes can't contain notes and Word gives every note a number of its
+// own, so other citations, including those within notes, are NOTEREF fields
+// showing the number of that note.
+func (c *Converter) noteReference(link *east.FootnoteLink, source []byte, style RunStyle) RunStyle {
+	footnote := c.footnotes[link.Index]
+	if footnote == nil {
+		return style.withText(fmt.Sprintf("[%d]", link.Index))
+	}
+	if _, created := c.noteIDs[link.Index]; created || c.inNote {
+		return c.noteRefField(link.Index, style)
+	}
+
+	kind := c.noteKind()
+	id := c.noteIDBase + len(c.notes) + 1
+	c.noteIDs[link.Index] = id
+	if _, ok := c.noteNumbers[link.Index]; !ok {
+		// Only cited from within footnotes until now
+		c.noteNumbers[link.Index] = len(c.notes) + 1
+		c.noteBookmarks[link.Index] = fmt.Sprintf("_Note%d_%d", len(c.notes)+1, link.Index)
+	}
+
+	// Notes live in a part of their own, with separate relationships
+	paragraphs, relationships, hyperlinks, mediaIndex := c.paragraphs, c.relationships, c.hyperlinks, c.mediaIndex
+	c.paragraphs, c.relationships, c.hyperlinks, c.mediaIndex = nil, c.noteRels, c.noteHyperlinks, c.noteMedia
+	c.inNote = true
+	c.processNode(footnote, source)
+	c.inNote = false
+	body := c.paragraphs
+	c.noteRels = c.relationships
+	c.paragraphs, c.relationships, c.hyperlinks, c.mediaIndex = paragraphs, relationships, hyperlinks, mediaIndex
+
+	// The note starts with its number, followed by a space
+	mark := fmt.Sprintf(`<w:r><w:rPr><w:rStyle w:val="%s"/></w:rPr><w:%sRef/></w:r><w:r><w:t xml:space="preserve"> </w:t></w:r>`, kind.RefStyle, kind.Name)
+	textStyle := `<w:pStyle w:val="` + kind.TextStyle + `"/>`
+	for i, para := range body {
+		body[i] = strings.ReplaceAll(para, `<w:pStyle w:val="BodyText"/>`, textStyle)
+	}
+	if len(body) > 0 && strings.HasPrefix(body[0], "<w:p>") && strings.Contains(body[0], "</w:pPr>") {
+		body[0] = strings.Replace(body[0], "</w:pPr>", "</w:pPr>\n      "+mark, 1)
+	} else {
+		body = append([]string{`<w:p><w:pPr>` + textStyle + `</w:pPr>` + mark + `</w:p>`}, body...)
+	}
+
+	c.notes = append(c.notes, fmt.Sprintf(`<w:%s w:id="%d">
+    %s
+  </w:%s>`, kind.Name, id, strings.Join(body, "\n    "), kind.Name))
+
+	bookmark := c.bookmarks
+	c.bookmarks++
+	return RunStyle{Note: fmt.Sprintf(`<w:bookmarkStart w:id="%d" w:name="%s"/><w:r><w:rPr><w:rStyle w:val="%s"/></w:rPr><w:%sReference w:id="%d"/></w:r><w:bookmarkEnd w:id="%d"/>`,
+		bookmark, c.noteBookmarks[link.Index], kind.RefStyle, kind.Name, id, bookmark)}
+}
+
+// noteRefField returns a NOTEREF field showing the number of a footnote's
+// note in the reference style. The number shown until Word updates the
+// field follows Word's default formats: 1, 2, 3 for footnotes and i, ii, iii
+// for endnotes.
+func (c *Converter) noteRefField(index int, style RunStyle) RunStyle {
+	number, ok := c.noteNumbers[index]
+	if !ok {
+		c.warn("footnote %d is only cited from other footnotes, so it is left out", index)
+		return style.withText(fmt.Sprintf("[%d]", index))
+	}
+	kind := c.noteKind()
+	text := fmt.Sprint(number)
+	if kind == endnoteKind {
+		text = lowerRoman(number)
+	}
+
+	rPr := `<w:rPr><w:rStyle w:val="` + kind.RefStyle + `"/></w:rPr>`
+	return RunStyle{Note: `<w:r>` + rPr + `<w:fldChar w:fldCharType="begin" w:dirty="true"/></w:r>` +
+		`<w:r>` + rPr + `<w:instrText xml:space="preserve"> NOTEREF ` + c.noteBookmarks[index] + ` \f \h </w:instrText></w:r>` +
+		`<w:r>` + rPr + `<w:fldChar w:fldCharType="separate"/></w:r>` +
+		`<w:r>` + rPr + `<w:t>` + text + `</w:t></w:r>` +
+		`<w:r>` + rPr + `<w:fldChar w:fldCharType="end"/></w:r>`}
+}
+
+// lowerRoman formats a positive number as a lowercase Roman numeral
+func lowerRoman(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	numerals := []string{"m", "cm", "d", "cd", "c", "xc", "l", "xl", "x", "ix", "v", "iv", "i"}
+	var b strings.Builder
+	for i, v := range values {
+		for n >= v {
+			b.WriteString(numerals[i])
+			n -= v
+		}
+	}
+	return b.String()
+}
+
+// notesXML creates the notes part. The root element and separators of a
+// template's notes part are kept; its other notes belonged to the template's
+// body and are dropped.
+func (c *Converter) notesXML(template string) string {
+	kind := c.noteKind()
+	startTag := strings.Replace(documentStartTag, "<w:document", "<w:"+kind.Name+"s", 1)
+	separators := fmt.Sprintf(`<w:%[1]s w:type="separator" w:id="-1">
+    <w:p><w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:r><w:separator/></w:r></w:p>
+  </w:%[1]s>
+  <w:%[1]s w:type="continuationSeparator" w:id="0">
+    <w:p><w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:r><w:continuationSeparator/></w:r></w:p>
+  </w:%[1]s>`, kind.Name)
+
+	if template != "" {
+		if tag := regexp.MustCompile(`<w:` + kind.Name + `s\b[^>]*>`).FindString(template); tag != "" {
+			startTag = withNamespaces(tag)
+		}
+		separators = strings.Join(kind.separatorPattern().FindAllString(template, -1), "\n  ")
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+%s
+  %s
+  %s
+</w:%ss>`, startTag, separators, strings.Join(c.notes, "\n  "), kind.Name)
+}
+
+// noteRelsXML creates the relationships part of the notes part
+func (c *Converter) noteRelsXML() string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + relationshipsXML(c.noteRels) + `
+</Relationships>`
+}
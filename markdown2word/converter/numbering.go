@@ -0,0 +1,139 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// Abstract numbering definitions written to word/numbering.xml
+const (
+	bulletAbstractNum  = 0
+	decimalAbstractNum = 1
+)
+
+// Word supports nine list levels (0-8)
+const maxListLevel = 8
+
+// numberingInstance is a <w:num> entry; each Markdown list gets one
+type numberingInstance struct {
+	AbstractNum int
+	Level       int
+	Start       int
+}
+
+// Bullet glyphs cycled through by nesting level
+var bulletGlyphs = []string{"•", "◦", "▪"}
+
+// Number formats and level text cycled through by nesting level
+var decimalFormats = []string{"decimal", "lowerLetter", "lowerRoman"}
+
+// addNumbering registers a numbering instance for a list and returns its ID
+func (c *Converter) addNumbering(node *ast.List, level int) int {
+	inst := numberingInstance{AbstractNum: bulletAbstractNum, Level: clampListLevel(level)}
+	if node.IsOrdered() {
+		inst.AbstractNum = decimalAbstractNum
+		inst.Start = node.Start
+	}
+	c.numbering = append(c.numbering, inst)
+	return c.numIDBase() + len(c.numbering)
+}
+
+// listIndent returns the left indent in twips of a list level's text
+func listIndent(level int) int {
+	return 720 + clampListLevel(level)*360
+}
+
+// clampListLevel limits a nesting depth to the levels Word supports
+func clampListLevel(level int) int {
+	if level > maxListLevel {
+		return maxListLevel
+	}
+	return level
+}
+
+// numberingXML creates word/numbering.xml with bullet and decimal definitions
+// and one numbering instance per list
+func (c *Converter) numberingXML() string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+		c.abstractNumsXML() + c.numsXML() + `
+</w:numbering>`
+}
+
+// abstractNumsXML creates the bullet and ordered list definitions
+func (c *Converter) abstractNumsXML() string {
+	var result strings.Builder
+
+	// Bullet list definition
+	result.WriteString(fmt.Sprintf(`
+  <w:abstractNum w:abstractNumId="%d">
+    <w:multiLevelType w:val="hybridMultilevel"/>`, c.abstractNumBase()+bulletAbstractNum))
+	for lvl := 0; lvl <= maxListLevel; lvl++ {
+		result.WriteString(fmt.Sprintf(`
+    <w:lvl w:ilvl="%d">
+      <w:start w:val="1"/>
+      <w:numFmt w:val="bullet"/>
+      <w:lvlText w:val="%s"/>
+      <w:lvlJc w:val="left"/>
+      <w:pPr><w:ind w:left="%d" w:hanging="360"/></w:pPr>
+    </w:lvl>`, lvl, bulletGlyphs[lvl%len(bulletGlyphs)], listIndent(lvl)))
+	}
+	result.WriteString(`
+  </w:abstractNum>`)
+
+	// Ordered list definition
+	result.WriteString(fmt.Sprintf(`
+  <w:abstractNum w:abstractNumId="%d">
+    <w:multiLevelType w:val="hybridMultilevel"/>`, c.abstractNumBase()+decimalAbstractNum))
+	for lvl := 0; lvl <= maxListLevel; lvl++ {
+		result.WriteString(fmt.Sprintf(`
+    <w:lvl w:ilvl="%d">
+      <w:start w:val="1"/>
+      <w:numFmt w:val="%s"/>
+      <w:lvlText w:val="%%%d."/>
+      <w:lvlJc w:val="left"/>
+      <w:pPr><w:ind w:left="%d" w:hanging="360"/></w:pPr>
+    </w:lvl>`, lvl, decimalFormats[lvl%len(decimalFormats)], lvl+1, listIndent(lvl)))
+	}
+	result.WriteString(`
+  </w:abstractNum>`)
+
+	return result.String()
+}
+
+// numsXML creates one numbering instance per list, restarting at the list's
+// start number
+func (c *Converter) numsXML() string {
+	var result strings.Builder
+	for i, inst := range c.numbering {
+		result.WriteString(fmt.Sprintf(`
+  <w:num w:numId="%d">
+    <w:abstractNumId w:val="%d"/>`, c.numIDBase()+i+1, c.abstractNumBase()+inst.AbstractNum))
+		if inst.AbstractNum == decimalAbstractNum {
+			result.WriteString(fmt.Sprintf(`
+    <w:lvlOverride w:ilvl="%d"><w:startOverride w:val="%d"/></w:lvlOverride>`, inst.Level, inst.Start))
+		}
+		result.WriteString(`
+  </w:num>`)
+	}
+	return result.String()
+}
+
+// numIDBase returns the offset for numbering instance IDs so they don't
+// clash with those of a reference document
+func (c *Converter) numIDBase() int {
+	if c.reference == nil {
+		return 0
+	}
+	return c.reference.maxNumID
+}
+
+// abstractNumBase returns the offset for abstract numbering definition IDs
+func (c *Converter) abstractNumBase() int {
+	if c.reference == nil {
+		return 0
+	}
+	return c.reference.maxAbstractNumID + 1
+}
@@ -0,0 +1,156 @@
+package converter
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// listParagraphs describes the numbered paragraphs of a document as
+// "text level numId"
+func listParagraphs(t *testing.T, document string) []string {
+	t.Helper()
+	var doc struct {
+		Paragraphs []struct {
+			Level *struct {
+				Val string `xml:"val,attr"`
+			} `xml:"pPr>numPr>ilvl"`
+			NumID struct {
+				Val string `xml:"val,attr"`
+			} `xml:"pPr>numPr>numId"`
+			Text []string `xml:"r>t"`
+		} `xml:"body>p"`
+	}
+	if err := xml.Unmarshal([]byte(document), &doc); err != nil {
+		t.Fatal(err)
+	}
+	var items []string
+	for _, p := range doc.Paragraphs {
+		if p.Level != nil {
+			items = append(items, strings.Join(p.Text, "")+" "+p.Level.Val+" "+p.NumID.Val)
+		}
+	}
+	return items
+}
+
+// numberingDefinitions describes the numbering instances of a numbering
+// part by ID as "abstractNumId" or "abstractNumId start=level:value", and
+// the formats of the abstract definitions by ID and level as
+// "numFmt lvlText"
+func numberingDefinitions(t *testing.T, part string) (nums, formats map[string]string) {
+	t.Helper()
+	type val struct {
+		Val string `xml:"val,attr"`
+	}
+	var numbering struct {
+		Abstract []struct {
+			ID     string `xml:"abstractNumId,attr"`
+			Levels []struct {
+				Level  string `xml:"ilvl,attr"`
+				Format val    `xml:"numFmt"`
+				Text   val    `xml:"lvlText"`
+			} `xml:"lvl"`
+		} `xml:"abstractNum"`
+		Nums []struct {
+			ID       string `xml:"numId,attr"`
+			Abstract val    `xml:"abstractNumId"`
+			Override *struct {
+				Level string `xml:"ilvl,attr"`
+				Start val    `xml:"startOverride"`
+			} `xml:"lvlOverride"`
+		} `xml:"num"`
+	}
+	if err := xml.Unmarshal([]byte(part), &numbering); err != nil {
+		t.Fatal(err)
+	}
+
+	nums, formats = map[string]string{}, map[string]string{}
+	for _, n := range numbering.Nums {
+		nums[n.ID] = n.Abstract.Val
+		if n.Override != nil {
+			nums[n.ID] += " start=" + n.Override.Level + ":" + n.Override.Start.Val
+		}
+	}
+	for _, a := range numbering.Abstract {
+		for _, l := range a.Levels {
+			formats[a.ID+"/"+l.Level] = l.Format.Val + " " + l.Text.Val
+		}
+	}
+	return nums, formats
+}
+
+func TestLists(t *testing.T) {
+	markdown := "1. One\n2. Two\n   - Bullet\n     1. Deep\n3. Three\n\n" +
+		"Text\n\n" +
+		"5. Five\n6. Six\n\n" +
+		"- Plain\n- Again\n"
+
+	parts := convertParts(t, markdown, Options{})
+
+	wantItems := []string{
+		"One 0 1", "Two 0 1", "Bullet 1 2", "Deep 2 3", "Three 0 1",
+		"Five 0 4", "Six 0 4",
+		"Plain 0 5", "Again 0 5",
+	}
+	if got := listParagraphs(t, parts["word/document.xml"]); !reflect.DeepEqual(got, wantItems) {
+		t.Errorf("got list items %q, want %q", got, wantItems)
+	}
+
+	// Ordered lists restart at their own start number at their own level
+	nums, formats := numberingDefinitions(t, parts["word/numbering.xml"])
+	wantNums := map[string]string{
+		"1": "1 start=0:1",
+		"2": "0",
+		"3": "1 start=2:1",
+		"4": "1 start=0:5",
+		"5": "0",
+	}
+	if !reflect.DeepEqual(nums, wantNums) {
+		t.Errorf("got numbering instances %q, want %q", nums, wantNums)
+	}
+	for level, want := range map[string]string{
+		"0/0": "bullet •", "0/1": "bullet ◦", "0/2": "bullet ▪", "0/3": "bullet •",
+		"1/0": "decimal %1.", "1/1": "lowerLetter %2.", "1/2": "lowerRoman %3.", "1/8": "lowerRoman %9.",
+	} {
+		if formats[level] != want {
+			t.Errorf("level %s: got %q, want %q", level, formats[level], want)
+		}
+	}
+
+	if !strings.Contains(parts["[Content_Types].xml"], `<Override PartName="/word/numbering.xml"`) {
+		t.Error("numbering part has no content type")
+	}
+	if !strings.Contains(parts["word/_rels/document.xml.rels"], `Target="numbering.xml"`) {
+		t.Error("numbering part not related to the document")
+	}
+}
+
+func TestListsDeeperThanWordLevels(t *testing.T) {
+	var markdown strings.Builder
+	for level := 0; level < 11; level++ {
+		markdown.WriteString(strings.Repeat("  ", level) + "- Item\n")
+	}
+
+	items := listParagraphs(t, convertParts(t, markdown.String(), Options{})["word/document.xml"])
+	if len(items) != 11 {
+		t.Fatalf("got %d list items, want 11", len(items))
+	}
+	for i, item := range items {
+		level := i
+		if level > maxListLevel {
+			level = maxListLevel
+		}
+		if fields := strings.Fields(item); fields[1] != strconv.Itoa(level) {
+			t.Errorf("item %d at level %s, want %d", i, fields[1], level)
+		}
+	}
+}
+
+func TestNoNumberingWithoutLists(t *testing.T) {
+	parts := convertParts(t, "Text\n", Options{})
+	if _, ok := parts["word/numbering.xml"]; ok {
+		t.Error("numbering part without lists")
+	}
+}
@@ -0,0 +1,831 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// texToOMML translates LaTeX math into the content of an <m:oMath> element
+// (Office Math Markup), so that equations stay editable in Word. It covers
+// the common subset used in technical writing: fractions, scripts, roots,
+// large operators, delimiters, accents, font commands, matrices and aligned
+// rows, Greek letters and operator symbols. Anything else is an error, and
+// the caller keeps the source as text.
+func texToOMML(tex string) (string, error) {
+	p := &texParser{src: []rune(tex)}
+	rows, err := p.parseRows("", true)
+	if err != nil {
+		return "", err
+	}
+	if p.pos < len(p.src) {
+		return "", fmt.Errorf("unexpected %q", string(p.src[p.pos]))
+	}
+	return rowsXML(rows), nil
+}
+
+// texParser is a recursive descent parser over the TeX source that builds
+// the OMML as it goes
+type texParser struct {
+	src []rune
+	pos int
+
+	// Run properties applied by font commands such as \mathbf
+	style string
+
+	// Set after & so the next run marks an alignment point
+	align bool
+}
+
+// stopAt lists the optional places where parsing an expression stops.
+// Closing braces, &, \\, \right and \end always stop it.
+type stopAt struct {
+	bracket  bool // ] ending the index of \sqrt[n]
+	relation bool // Relation symbols ending the operand of a large operator
+}
+
+// texRow is one row of an environment or of display math, split at &
+type texRow []string
+
+// rowsXML joins rows into a single expression, an equation array when there
+// are several rows. Cells of a row are already separated by alignment marks.
+func rowsXML(rows []texRow) string {
+	if len(rows) == 1 {
+		return strings.Join(rows[0], "")
+	}
+	var b strings.Builder
+	b.WriteString("<m:eqArr>")
+	for _, row := range rows {
+		b.WriteString("<m:e>" + strings.Join(row, "") + "</m:e>")
+	}
+	b.WriteString("</m:eqArr>")
+	return b.String()
+}
+
+// parseRows parses cells separated by & and rows separated by \\ until
+// \end{env}, or until the end of the source when env is empty. With align
+// set, & marks where the rows of an equation array line up.
+func (p *texParser) parseRows(env string, align bool) ([]texRow, error) {
+	var rows []texRow
+	row := texRow{}
+	for {
+		cell, err := p.parseExpr(stopAt{})
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, cell)
+
+		p.skipSpace()
+		switch {
+		case p.consume("&"):
+			p.align = align
+			continue
+		case p.consume(`\\`):
+			rows = append(rows, row)
+			row = texRow{}
+			continue
+		case env == "" && p.pos >= len(p.src):
+		case env != "" && p.consumeCommand("end"):
+			name, err := p.readName()
+			if err != nil {
+				return nil, err
+			}
+			if name != env {
+				return nil, fmt.Errorf(`\begin{%s} ended by \end{%s}`, env, name)
+			}
+		case p.pos >= len(p.src):
+			return nil, fmt.Errorf(`missing \end{%s}`, env)
+		default:
+			return nil, fmt.Errorf("unexpected %s", p.describe())
+		}
+
+		// A trailing \\ doesn't start another row
+		if len(row) > 1 || row[0] != "" || len(rows) == 0 {
+			rows = append(rows, row)
+		}
+		p.align = false
+		return rows, nil
+	}
+}
+
+// parseExpr parses elements up to the end of the source or a stop
+func (p *texParser) parseExpr(stop stopAt) (string, error) {
+	var b strings.Builder
+	for {
+		p.skipSpace()
+		if p.atStop(stop) {
+			return b.String(), nil
+		}
+		elem, err := p.parseScripted()
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(elem)
+	}
+}
+
+// atStop reports whether parsing an expression should stop here
+func (p *texParser) atStop(stop stopAt) bool {
+	if p.pos >= len(p.src) {
+		return true
+	}
+	switch c := p.src[p.pos]; {
+	case c == '}' || c == '&':
+		return true
+	case c == ']':
+		return stop.bracket
+	case c == '\\':
+		name := p.peekCommand()
+		if name == `\` || name == "right" || name == "end" {
+			return true
+		}
+		_, isRelation := texRelations[name]
+		return stop.relation && isRelation
+	default:
+		return stop.relation && strings.ContainsRune("=<>", c)
+	}
+}
+
+// parseScripted parses an element followed by any subscript, superscript
+// and primes
+func (p *texParser) parseScripted() (string, error) {
+	if name := p.peekCommand(); name != "" {
+		if chr, ok := texNary[name]; ok {
+			p.pos += len([]rune(name)) + 1
+			return p.parseNary(chr)
+		}
+		if _, ok := texFunctions[name]; ok {
+			p.pos += len([]rune(name)) + 1
+			return p.parseFunction(name)
+		}
+	}
+
+	base, err := p.parseAtom()
+	if err != nil {
+		return "", err
+	}
+	sub, sup, err := p.parseScripts()
+	if err != nil {
+		return "", err
+	}
+	return scripted(base, sub, sup), nil
+}
+
+// scripted attaches a subscript and superscript, either possibly empty, to
+// a base
+func scripted(base, sub, sup string) string {
+	switch {
+	case sub != "" && sup != "":
+		return "<m:sSubSup><m:e>" + base + "</m:e><m:sub>" + sub + "</m:sub><m:sup>" + sup + "</m:sup></m:sSubSup>"
+	case sub != "":
+		return "<m:sSub><m:e>" + base + "</m:e><m:sub>" + sub + "</m:sub></m:sSub>"
+	case sup != "":
+		return "<m:sSup><m:e>" + base + "</m:e><m:sup>" + sup + "</m:sup></m:sSup>"
+	}
+	return base
+}
+
+// parseScripts parses the _ and ^ scripts and primes following an element
+func (p *texParser) parseScripts() (sub, sup string, err error) {
+	hasSup := false
+	for {
+		p.skipSpace()
+		switch {
+		case p.consume("_"):
+			if sub != "" {
+				return "", "", fmt.Errorf("double subscript")
+			}
+			if sub, err = p.parseArg(); err != nil {
+				return "", "", err
+			}
+		case p.consume("^"):
+			if hasSup {
+				return "", "", fmt.Errorf("double superscript")
+			}
+			arg, err := p.parseArg()
+			if err != nil {
+				return "", "", err
+			}
+			sup += arg
+			hasSup = true
+		case p.consume("'"):
+			sup += p.run("′")
+		case p.consumeCommand("limits") || p.consumeCommand("nolimits"):
+		default:
+			return sub, sup, nil
+		}
+	}
+}
+
+// parseArg parses a command argument: a group or a single token
+func (p *texParser) parseArg() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return "", fmt.Errorf("missing argument")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		return p.parseGroup()
+	case c == '\\':
+		return p.parseAtom()
+	case c == '}' || c == '&' || c == '^' || c == '_':
+		return "", fmt.Errorf("missing argument")
+	default:
+		p.pos++
+		return p.symbol(c), nil
+	}
+}
+
+// parseGroup parses a {...} group
+func (p *texParser) parseGroup() (string, error) {
+	if !p.consume("{") {
+		return "", fmt.Errorf("expected {")
+	}
+	content, err := p.parseExpr(stopAt{})
+	if err != nil {
+		return "", err
+	}
+	if !p.consume("}") {
+		return "", fmt.Errorf("missing }")
+	}
+	return content, nil
+}
+
+// parseAtom parses one element without scripts
+func (p *texParser) parseAtom() (string, error) {
+	c := p.src[p.pos]
+	switch {
+	case c == '{':
+		return p.parseGroup()
+	case c == '\\':
+		p.pos++
+		name := p.readCommandName()
+		return p.parseCommand(name)
+	case c == '^' || c == '_':
+		// A script without a base, e.g. {}^{14}C
+		return "", nil
+	case unicode.IsDigit(c):
+		start := p.pos
+		for p.pos < len(p.src) && (unicode.IsDigit(p.src[p.pos]) || p.src[p.pos] == '.' && p.pos+1 < len(p.src) && unicode.IsDigit(p.src[p.pos+1])) {
+			p.pos++
+		}
+		return p.run(string(p.src[start:p.pos])), nil
+	default:
+		p.pos++
+		return p.symbol(c), nil
+	}
+}
+
+// symbol returns the run for a single character of the source
+func (p *texParser) symbol(c rune) string {
+	switch c {
+	case '-':
+		return p.run("−")
+	case '*':
+		return p.run("∗")
+	case '~':
+		return p.run("\u00a0")
+	}
+	return p.run(string(c))
+}
+
+// parseCommand handles a command whose backslash and name have been read
+func (p *texParser) parseCommand(name string) (string, error) {
+	if s, ok := texSymbols[name]; ok {
+		return p.run(s), nil
+	}
+	if s, ok := texRelations[name]; ok {
+		return p.run(s), nil
+	}
+	if s, ok := texUprightSymbols[name]; ok {
+		return p.styledRun(s, `<m:sty m:val="p"/>`), nil
+	}
+	if s, ok := texSpaces[name]; ok {
+		if s == "" {
+			return "", nil
+		}
+		return p.run(s), nil
+	}
+	if chr, ok := texAccents[name]; ok {
+		arg, err := p.parseArg()
+		if err != nil {
+			return "", err
+		}
+		return `<m:acc><m:accPr><m:chr m:val="` + chr + `"/></m:accPr><m:e>` + arg + `</m:e></m:acc>`, nil
+	}
+	if style, ok := texFonts[name]; ok {
+		saved := p.style
+		p.style = style
+		arg, err := p.parseArg()
+		p.style = saved
+		return arg, err
+	}
+	if format, ok := texTextCommands[name]; ok {
+		text, err := p.readRawGroup()
+		if err != nil {
+			return "", err
+		}
+		run := p.styledRun(text, "<m:nor/>")
+		if format != "" {
+			run = strings.Replace(run, "<m:t ", "<w:rPr>"+format+"</w:rPr><m:t ", 1)
+		}
+		return run, nil
+	}
+	if _, ok := texSizes[name]; ok {
+		// Delimiter sizes are left to Word
+		return "", nil
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num, err := p.parseArg()
+		if err != nil {
+			return "", err
+		}
+		den, err := p.parseArg()
+		if err != nil {
+			return "", err
+		}
+		return "<m:f><m:num>" + num + "</m:num><m:den>" + den + "</m:den></m:f>", nil
+
+	case "binom", "dbinom", "tbinom":
+		top, err := p.parseArg()
+		if err != nil {
+			return "", err
+		}
+		bottom, err := p.parseArg()
+		if err != nil {
+			return "", err
+		}
+		return `<m:d><m:e><m:f><m:fPr><m:type m:val="noBar"/></m:fPr><m:num>` + top + "</m:num><m:den>" + bottom + "</m:den></m:f></m:e></m:d>", nil
+
+	case "sqrt":
+		p.skipSpace()
+		if p.consume("[") {
+			deg, err := p.parseExpr(stopAt{bracket: true})
+			if err != nil {
+				return "", err
+			}
+			if !p.consume("]") {
+				return "", fmt.Errorf(`missing ] in \sqrt`)
+			}
+			arg, err := p.parseArg()
+			if err != nil {
+				return "", err
+			}
+			return "<m:rad><m:deg>" + deg + "</m:deg><m:e>" + arg + "</m:e></m:rad>", nil
+		}
+		arg, err := p.parseArg()
+		if err != nil {
+			return "", err
+		}
+		return `<m:rad><m:radPr><m:degHide m:val="1"/></m:radPr><m:deg/><m:e>` + arg + "</m:e></m:rad>", nil
+
+	case "overline", "underline":
+		arg, err := p.parseArg()
+		if err != nil {
+			return "", err
+		}
+		pos := "top"
+		if name == "underline" {
+			pos = "bot"
+		}
+		return `<m:bar><m:barPr><m:pos m:val="` + pos + `"/></m:barPr><m:e>` + arg + "</m:e></m:bar>", nil
+
+	case "left":
+		return p.parseDelimited()
+
+	case "middle":
+		delim, err := p.readDelimiter()
+		if err != nil {
+			return "", err
+		}
+		return p.run(delim), nil
+
+	case "begin":
+		return p.parseEnvironment()
+	}
+
+	return "", fmt.Errorf(`unsupported command \%s`, name)
+}
+
+// parseNary parses a large operator such as \sum or \int with its limits.
+// Its operand runs up to the next relation or the end of the expression.
+func (p *texParser) parseNary(chr texNaryOp) (string, error) {
+	sub, sup, err := p.parseScripts()
+	if err != nil {
+		return "", err
+	}
+	operand, err := p.parseExpr(stopAt{relation: true})
+	if err != nil {
+		return "", err
+	}
+
+	var pr strings.Builder
+	pr.WriteString(`<m:chr m:val="` + chr.chr + `"/>`)
+	pr.WriteString(`<m:limLoc m:val="` + chr.limLoc + `"/>`)
+	if sub == "" {
+		pr.WriteString(`<m:subHide m:val="1"/>`)
+	}
+	if sup == "" {
+		pr.WriteString(`<m:supHide m:val="1"/>`)
+	}
+	return "<m:nary><m:naryPr>" + pr.String() + "</m:naryPr><m:sub>" + sub + "</m:sub><m:sup>" + sup + "</m:sup><m:e>" + operand + "</m:e></m:nary>", nil
+}
+
+// parseFunction parses a function name such as \sin or \lim and its
+// argument. Limits of \lim-like functions go below the name.
+func (p *texParser) parseFunction(name string) (string, error) {
+	fname := p.styledRun(name, `<m:sty m:val="p"/>`)
+	sub, sup, err := p.parseScripts()
+	if err != nil {
+		return "", err
+	}
+	if texFunctions[name] && sub != "" {
+		fname = "<m:limLow><m:e>" + fname + "</m:e><m:lim>" + sub + "</m:lim></m:limLow>"
+		sub = ""
+	}
+	fname = scripted(fname, sub, sup)
+
+	p.skipSpace()
+	arg := ""
+	if !p.atStop(stopAt{relation: true}) && !strings.ContainsRune("+-,;", p.src[p.pos]) {
+		if arg, err = p.parseScripted(); err != nil {
+			return "", err
+		}
+	}
+	return "<m:func><m:fName>" + fname + "</m:fName><m:e>" + arg + "</m:e></m:func>", nil
+}
+
+// parseDelimited parses \left( ... \right) after \left has been read
+func (p *texParser) parseDelimited() (string, error) {
+	open, err := p.readDelimiter()
+	if err != nil {
+		return "", err
+	}
+	content, err := p.parseExpr(stopAt{})
+	if err != nil {
+		return "", err
+	}
+	if !p.consumeCommand("right") {
+		return "", fmt.Errorf(`\left without \right`)
+	}
+	closing, err := p.readDelimiter()
+	if err != nil {
+		return "", err
+	}
+	return delimited(open, closing, content), nil
+}
+
+// delimited surrounds content with delimiters, which may be empty
+func delimited(open, closing, content string) string {
+	return `<m:d><m:dPr><m:begChr m:val="` + escapeXML(open) + `"/><m:endChr m:val="` + escapeXML(closing) + `"/></m:dPr><m:e>` + content + "</m:e></m:d>"
+}
+
+// readDelimiter reads the delimiter after \left, \right or \middle, where
+// "." stands for none
+func (p *texParser) readDelimiter() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return "", fmt.Errorf("missing delimiter")
+	}
+	c := p.src[p.pos]
+	p.pos++
+	switch c {
+	case '.':
+		return "", nil
+	case '\\':
+		name := p.readCommandName()
+		if d, ok := texDelimiters[name]; ok {
+			return d, nil
+		}
+		return "", fmt.Errorf(`unsupported delimiter \%s`, name)
+	}
+	return string(c), nil
+}
+
+// parseEnvironment parses \begin{env} ... \end{env} after \begin has been
+// read
+func (p *texParser) parseEnvironment() (string, error) {
+	env, err := p.readName()
+	if err != nil {
+		return "", err
+	}
+
+	switch env {
+	case "aligned", "align", "align*", "gathered", "gather", "gather*", "split", "equation", "equation*":
+		rows, err := p.parseRows(env, true)
+		if err != nil {
+			return "", err
+		}
+		return rowsXML(rows), nil
+	}
+
+	delims, ok := texMatrices[env]
+	if !ok {
+		return "", fmt.Errorf("unsupported environment %s", env)
+	}
+	if env == "array" {
+		// Column alignments are left to Word
+		if _, err := p.readRawGroup(); err != nil {
+			return "", err
+		}
+	}
+
+	// Cells are separate elements, so & needs no alignment mark
+	rows, err := p.parseRows(env, false)
+	if err != nil {
+		return "", err
+	}
+	matrix := matrixXML(rows, env == "cases")
+	if delims[0] == "" && delims[1] == "" {
+		return matrix, nil
+	}
+	return delimited(delims[0], delims[1], matrix), nil
+}
+
+// matrixXML creates an <m:m> matrix, padding short rows with empty cells as
+// Word requires the same number in every row
+func matrixXML(rows []texRow, leftAligned bool) string {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	jc := "center"
+	if leftAligned {
+		jc = "left"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`<m:m><m:mPr><m:mcs><m:mc><m:mcPr><m:count m:val="%d"/><m:mcJc m:val="%s"/></m:mcPr></m:mc></m:mcs></m:mPr>`, columns, jc))
+	for _, row := range rows {
+		b.WriteString("<m:mr>")
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			b.WriteString("<m:e>" + cell + "</m:e>")
+		}
+		b.WriteString("</m:mr>")
+	}
+	b.WriteString("</m:m>")
+	return b.String()
+}
+
+// run creates a math run in the current font style
+func (p *texParser) run(text string) string {
+	return p.styledRun(text, p.style)
+}
+
+// styledRun creates a math run with the given run properties, marking an
+// alignment point if the run follows &
+func (p *texParser) styledRun(text, style string) string {
+	if p.align {
+		style += "<m:aln/>"
+		p.align = false
+	}
+	rPr := ""
+	if style != "" {
+		rPr = "<m:rPr>" + style + "</m:rPr>"
+	}
+	return "<m:r>" + rPr + `<m:t xml:space="preserve">` + escapeXML(text) + "</m:t></m:r>"
+}
+
+// skipSpace skips whitespace, which TeX ignores in math
+func (p *texParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// consume skips s if the source continues with it
+func (p *texParser) consume(s string) bool {
+	r := []rune(s)
+	if p.pos+len(r) > len(p.src) || string(p.src[p.pos:p.pos+len(r)]) != s {
+		return false
+	}
+	p.pos += len(r)
+	return true
+}
+
+// peekCommand returns the name of the command at the current position, if
+// any, without consuming it
+func (p *texParser) peekCommand() string {
+	if p.pos >= len(p.src) || p.src[p.pos] != '\\' {
+		return ""
+	}
+	saved := p.pos
+	p.pos++
+	name := p.readCommandName()
+	p.pos = saved
+	return name
+}
+
+// consumeCommand skips the command \name if it comes next
+func (p *texParser) consumeCommand(name string) bool {
+	p.skipSpace()
+	if p.peekCommand() != name {
+		return false
+	}
+	p.pos += len([]rune(name)) + 1
+	return true
+}
+
+// readCommandName reads a command name after its backslash: a run of
+// letters, or a single other character
+func (p *texParser) readCommandName() string {
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	start := p.pos
+	for p.pos < len(p.src) && unicode.IsLetter(p.src[p.pos]) && p.src[p.pos] < unicode.MaxASCII {
+		p.pos++
+	}
+	if p.pos == start {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// readName reads the {name} of an environment
+func (p *texParser) readName() (string, error) {
+	name, err := p.readRawGroup()
+	return strings.TrimSpace(name), err
+}
+
+// readRawGroup reads the literal text of a {...} group, as used by \text
+func (p *texParser) readRawGroup() (string, error) {
+	p.skipSpace()
+	if !p.consume("{") {
+		return "", fmt.Errorf("expected {")
+	}
+	start, depth := p.pos, 0
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				text := string(p.src[start:p.pos])
+				p.pos++
+				return text, nil
+			}
+			depth--
+		}
+	}
+	return "", fmt.Errorf("missing }")
+}
+
+// describe names the token at the current position for error messages
+func (p *texParser) describe() string {
+	if name := p.peekCommand(); name != "" {
+		return `\` + name
+	}
+	return fmt.Sprintf("%q", string(p.src[p.pos]))
+}
+
+// texNaryOp is the character and limit placement of a large operator
+type texNaryOp struct {
+	chr    string
+	limLoc string // "undOvr" for limits above and below, "subSup" beside
+}
+
+// Large operators
+var texNary = map[string]texNaryOp{
+	"sum":       {"∑", "undOvr"},
+	"prod":      {"∏", "undOvr"},
+	"coprod":    {"∐", "undOvr"},
+	"bigcup":    {"⋃", "undOvr"},
+	"bigcap":    {"⋂", "undOvr"},
+	"bigvee":    {"⋁", "undOvr"},
+	"bigwedge":  {"⋀", "undOvr"},
+	"bigoplus":  {"⨁", "undOvr"},
+	"bigotimes": {"⨂", "undOvr"},
+	"int":       {"∫", "subSup"},
+	"iint":      {"∬", "subSup"},
+	"iiint":     {"∭", "subSup"},
+	"oint":      {"∮", "subSup"},
+}
+
+// Function names set upright; those mapped to true take their subscript
+// below the name, like \lim_{x \to 0}
+var texFunctions = map[string]bool{
+	"sin": false, "cos": false, "tan": false, "cot": false, "sec": false, "csc": false,
+	"arcsin": false, "arccos": false, "arctan": false,
+	"sinh": false, "cosh": false, "tanh": false, "coth": false,
+	"log": false, "ln": false, "lg": false, "exp": false,
+	"det": false, "dim": false, "ker": false, "deg": false, "arg": false, "hom": false,
+	"gcd": true, "lim": true, "liminf": true, "limsup": true, "max": true, "min": true,
+	"sup": true, "inf": true, "Pr": true,
+}
+
+// Relation symbols, which end the operand of a large operator
+var texRelations = map[string]string{
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠",
+	"approx": "≈", "equiv": "≡", "sim": "∼", "simeq": "≃", "cong": "≅",
+	"propto": "∝", "ll": "≪", "gg": "≫", "prec": "≺", "succ": "≻",
+	"in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂", "subseteq": "⊆",
+	"supset": "⊃", "supseteq": "⊇", "perp": "⊥", "parallel": "∥", "mid": "∣",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←",
+	"Rightarrow": "⇒", "Leftarrow": "⇐", "Leftrightarrow": "⇔",
+	"leftrightarrow": "↔", "mapsto": "↦", "implies": "⟹", "iff": "⟺",
+	"longrightarrow": "⟶", "longleftarrow": "⟵", "uparrow": "↑", "downarrow": "↓",
+}
+
+// Greek letters and other symbols
+var texSymbols = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ",
+	"varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ", "varphi": "φ",
+	"chi": "χ", "psi": "ψ", "omega": "ω",
+
+	"pm": "±", "mp": "∓", "times": "×", "div": "÷", "cdot": "⋅", "ast": "∗",
+	"star": "⋆", "circ": "∘", "bullet": "∙", "oplus": "⊕", "otimes": "⊗",
+	"cup": "∪", "cap": "∩", "setminus": "∖", "wedge": "∧", "land": "∧",
+	"vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬",
+	"infty": "∞", "partial": "∂", "nabla": "∇", "forall": "∀", "exists": "∃",
+	"nexists": "∄", "emptyset": "∅", "varnothing": "∅", "angle": "∠",
+	"prime": "′", "hbar": "ℏ", "ell": "ℓ", "Re": "ℜ", "Im": "ℑ", "aleph": "ℵ",
+	"ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋",
+	"lceil": "⌈", "rceil": "⌉", "vert": "|", "Vert": "‖",
+	"{": "{", "}": "}", "|": "‖", "%": "%", "$": "$", "#": "#", "&": "&", "_": "_",
+}
+
+// Symbols set upright, like capital Greek letters in TeX
+var texUprightSymbols = map[string]string{
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+}
+
+// Spacing commands
+var texSpaces = map[string]string{
+	",": "\u2009", ":": "\u205f", ">": "\u205f", ";": "\u2004", " ": " ",
+	"quad": "\u2003", "qquad": "\u2003\u2003", "!": "",
+}
+
+// Accents, as combining characters
+var texAccents = map[string]string{
+	"hat": "\u0302", "widehat": "\u0302", "check": "\u030c", "tilde": "\u0303",
+	"widetilde": "\u0303", "acute": "\u0301", "grave": "\u0300", "dot": "\u0307",
+	"ddot": "\u0308", "breve": "\u0306", "bar": "\u0305", "vec": "\u20d7",
+}
+
+// Font commands and the math run properties they apply
+var texFonts = map[string]string{
+	"mathrm":       `<m:sty m:val="p"/>`,
+	"operatorname": `<m:sty m:val="p"/>`,
+	"mathit":       `<m:sty m:val="i"/>`,
+	"mathbf":       `<m:sty m:val="b"/>`,
+	"boldsymbol":   `<m:sty m:val="bi"/>`,
+	"bm":           `<m:sty m:val="bi"/>`,
+	"mathbb":       `<m:scr m:val="double-struck"/><m:sty m:val="p"/>`,
+	"mathcal":      `<m:scr m:val="script"/><m:sty m:val="p"/>`,
+	"mathfrak":     `<m:scr m:val="fraktur"/><m:sty m:val="p"/>`,
+	"mathsf":       `<m:scr m:val="sans-serif"/><m:sty m:val="p"/>`,
+	"mathtt":       `<m:scr m:val="monospace"/><m:sty m:val="p"/>`,
+}
+
+// Commands whose argument is text rather than math, and the character
+// formatting they apply
+var texTextCommands = map[string]string{
+	"text":       "",
+	"textrm":     "",
+	"textnormal": "",
+	"mbox":       "",
+	"textit":     "<w:i/>",
+	"textbf":     "<w:b/>",
+}
+
+// Delimiter size commands
+var texSizes = map[string]bool{
+	"big": true, "Big": true, "bigg": true, "Bigg": true,
+	"bigl": true, "Bigl": true, "biggl": true, "Biggl": true,
+	"bigr": true, "Bigr": true, "biggr": true, "Biggr": true,
+	"bigm": true, "Bigm": true, "displaystyle": true, "textstyle": true,
+}
+
+// Delimiters given as commands after \left and \right
+var texDelimiters = map[string]string{
+	"{": "{", "}": "}", "|": "‖", "langle": "⟨", "rangle": "⟩",
+	"lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉",
+	"vert": "|", "Vert": "‖", "lvert": "|", "rvert": "|", "lVert": "‖", "rVert": "‖",
+}
+
+// Matrix environments and their opening and closing delimiters
+var texMatrices = map[string][2]string{
+	"matrix":      {"", ""},
+	"smallmatrix": {"", ""},
+	"array":       {"", ""},
+	"pmatrix":     {"(", ")"},
+	"bmatrix":     {"[", "]"},
+	"Bmatrix":     {"{", "}"},
+	"vmatrix":     {"|", "|"},
+	"Vmatrix":     {"‖", "‖"},
+	"cases":       {"{", ""},
+}
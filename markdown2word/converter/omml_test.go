@@ -0,0 +1,110 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+// r returns a plain math run
+func r(text string) string {
+	return `<m:r><m:t xml:space="preserve">` + text + "</m:t></m:r>"
+}
+
+// rs returns a math run with run properties
+func rs(text, props string) string {
+	return "<m:r><m:rPr>" + props + `</m:rPr><m:t xml:space="preserve">` + text + "</m:t></m:r>"
+}
+
+// runs returns a plain run per character of text
+func runs(text string) string {
+	var b strings.Builder
+	for _, c := range text {
+		b.WriteString(r(string(c)))
+	}
+	return b.String()
+}
+
+func TestTexToOMML(t *testing.T) {
+	matrixPr := func(columns, jc string) string {
+		return `<m:mPr><m:mcs><m:mc><m:mcPr><m:count m:val="` + columns + `"/><m:mcJc m:val="` + jc + `"/></m:mcPr></m:mc></m:mcs></m:mPr>`
+	}
+	upright := `<m:sty m:val="p"/>`
+
+	tests := []struct {
+		name string
+		tex  string
+		want string
+	}{
+		{"symbols", `x + 12.5 - \alpha \leq \Gamma`, r("x") + r("+") + r("12.5") + r("−") + r("α") + r("≤") + rs("Γ", upright)},
+		{"fraction", `\frac{a+1}{b}`, "<m:f><m:num>" + runs("a+1") + "</m:num><m:den>" + r("b") + "</m:den></m:f>"},
+		{"nested fraction", `\dfrac1{\frac{x}{2}}`, "<m:f><m:num>" + r("1") + "</m:num><m:den><m:f><m:num>" + r("x") + "</m:num><m:den>" + r("2") + "</m:den></m:f></m:den></m:f>"},
+		{"binomial", `\binom{n}{k}`, `<m:d><m:e><m:f><m:fPr><m:type m:val="noBar"/></m:fPr><m:num>` + r("n") + "</m:num><m:den>" + r("k") + "</m:den></m:f></m:e></m:d>"},
+		{"subscript", `x_{ij}`, "<m:sSub><m:e>" + r("x") + "</m:e><m:sub>" + runs("ij") + "</m:sub></m:sSub>"},
+		{"superscript", `e^{-x}`, "<m:sSup><m:e>" + r("e") + "</m:e><m:sup>" + r("−") + r("x") + "</m:sup></m:sSup>"},
+		{"both scripts", `x_i^2`, "<m:sSubSup><m:e>" + r("x") + "</m:e><m:sub>" + r("i") + "</m:sub><m:sup>" + r("2") + "</m:sup></m:sSubSup>"},
+		{"prime", `f'`, "<m:sSup><m:e>" + r("f") + "</m:e><m:sup>" + r("′") + "</m:sup></m:sSup>"},
+		{"script without base", `{}^{14}C`, "<m:sSup><m:e></m:e><m:sup>" + r("14") + "</m:sup></m:sSup>" + r("C")},
+		{"square root", `\sqrt{x}`, `<m:rad><m:radPr><m:degHide m:val="1"/></m:radPr><m:deg/><m:e>` + r("x") + "</m:e></m:rad>"},
+		{"nth root", `\sqrt[3]{x}`, "<m:rad><m:deg>" + r("3") + "</m:deg><m:e>" + r("x") + "</m:e></m:rad>"},
+		{"delimiters", `\left( x \right]`, `<m:d><m:dPr><m:begChr m:val="("/><m:endChr m:val="]"/></m:dPr><m:e>` + r("x") + "</m:e></m:d>"},
+		{"invisible delimiter", `\left. x \right|`, `<m:d><m:dPr><m:begChr m:val=""/><m:endChr m:val="|"/></m:dPr><m:e>` + r("x") + "</m:e></m:d>"},
+		{"command delimiters", `\left\langle a \middle| b \right\rangle`, `<m:d><m:dPr><m:begChr m:val="⟨"/><m:endChr m:val="⟩"/></m:dPr><m:e>` + r("a") + r("|") + r("b") + "</m:e></m:d>"},
+		{"matrix", `\begin{pmatrix} a & b \\ c \end{pmatrix}`, `<m:d><m:dPr><m:begChr m:val="("/><m:endChr m:val=")"/></m:dPr><m:e><m:m>` + matrixPr("2", "center") +
+			"<m:mr><m:e>" + r("a") + "</m:e><m:e>" + r("b") + "</m:e></m:mr><m:mr><m:e>" + r("c") + "</m:e><m:e></m:e></m:mr></m:m></m:e></m:d>"},
+		{"cases", `\begin{cases} a & x>0 \\ b \end{cases}`, `<m:d><m:dPr><m:begChr m:val="{"/><m:endChr m:val=""/></m:dPr><m:e><m:m>` + matrixPr("2", "left") +
+			"<m:mr><m:e>" + r("a") + "</m:e><m:e>" + r("x") + r("&gt;") + r("0") + "</m:e></m:mr><m:mr><m:e>" + r("b") + "</m:e><m:e></m:e></m:mr></m:m></m:e></m:d>"},
+		{"aligned rows", `a &= b \\ c &= d \\`, "<m:eqArr><m:e>" + r("a") + rs("=", "<m:aln/>") + r("b") + "</m:e><m:e>" + r("c") + rs("=", "<m:aln/>") + r("d") + "</m:e></m:eqArr>"},
+		{"accent", `\hat{x}`, `<m:acc><m:accPr><m:chr m:val="` + "\u0302" + `"/></m:accPr><m:e>` + r("x") + "</m:e></m:acc>"},
+		{"vector", `\vec v`, `<m:acc><m:accPr><m:chr m:val="` + "\u20d7" + `"/></m:accPr><m:e>` + r("v") + "</m:e></m:acc>"},
+		{"bar", `\overline{AB}`, `<m:bar><m:barPr><m:pos m:val="top"/></m:barPr><m:e>` + runs("AB") + "</m:e></m:bar>"},
+		{"large operator", `\sum_{i=1}^n i = 1`, `<m:nary><m:naryPr><m:chr m:val="∑"/><m:limLoc m:val="undOvr"/></m:naryPr><m:sub>` + runs("i=1") + "</m:sub><m:sup>" + r("n") + "</m:sup><m:e>" + r("i") + "</m:e></m:nary>" + runs("=1")},
+		{"integral without limits", `\int f`, `<m:nary><m:naryPr><m:chr m:val="∫"/><m:limLoc m:val="subSup"/><m:subHide m:val="1"/><m:supHide m:val="1"/></m:naryPr><m:sub></m:sub><m:sup></m:sup><m:e>` + r("f") + "</m:e></m:nary>"},
+		{"function", `\sin x`, "<m:func><m:fName>" + rs("sin", upright) + "</m:fName><m:e>" + r("x") + "</m:e></m:func>"},
+		{"limit", `\lim_{x \to 0} f`, "<m:func><m:fName><m:limLow><m:e>" + rs("lim", upright) + "</m:e><m:lim>" + r("x") + r("→") + r("0") + "</m:lim></m:limLow></m:fName><m:e>" + r("f") + "</m:e></m:func>"},
+		{"font", `\mathbf{x} \mathbb{R}`, rs("x", `<m:sty m:val="b"/>`) + rs("R", `<m:scr m:val="double-struck"/><m:sty m:val="p"/>`)},
+		{"text", `\text{if } x`, rs("if ", "<m:nor/>") + r("x")},
+		{"bold text", `\textbf{a<b}`, `<m:r><m:rPr><m:nor/></m:rPr><w:rPr><w:b/></w:rPr><m:t xml:space="preserve">a&lt;b</m:t></m:r>`},
+		{"spacing", `a\,b\!c`, r("a") + r(" ") + r("b") + r("c")},
+		{"delimiter size", `\big( x`, r("(") + r("x")},
+	}
+
+	for _, tt := range tests {
+		got, err := texToOMML(tt.tex)
+		if err != nil {
+			t.Errorf("%s: %s: %v", tt.name, tt.tex, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: %s\n got %s\nwant %s", tt.name, tt.tex, got, tt.want)
+		}
+	}
+}
+
+func TestTexToOMMLErrors(t *testing.T) {
+	tests := []struct {
+		tex  string
+		want string
+	}{
+		{`\foo{x}`, `unsupported command \foo`},
+		{`\frac{a}`, "missing argument"},
+		{`x^`, "missing argument"},
+		{`x_1_2`, "double subscript"},
+		{`x^1^2`, "double superscript"},
+		{`{x`, "missing }"},
+		{`}`, `unexpected "}"`},
+		{`\sqrt[3 x`, `missing ] in \sqrt`},
+		{`\left( x`, `\left without \right`},
+		{`\left\foo x \right)`, `unsupported delimiter \foo`},
+		{`\begin{foo} x \end{foo}`, "unsupported environment foo"},
+		{`\begin{pmatrix} a \end{bmatrix}`, `\begin{pmatrix} ended by \end{bmatrix}`},
+		{`\begin{matrix} a`, `missing \end{matrix}`},
+		{`a \right)`, `unexpected \right`},
+	}
+
+	for _, tt := range tests {
+		_, err := texToOMML(tt.tex)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: got error %v, want %q", tt.tex, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package converter
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// referenceDocx is an existing Word document used as a template. Everything
+// except the body content is carried over into the generated document.
+type referenceDocx struct {
+	parts map[string][]byte
+	names []string // Part names in archive order
+
+	// Root element and final section properties of word/document.xml
+	documentStart string
+	sectPr        string
+
+	// Usable page width in twips, or 0 if the template doesn't say
+	contentWidth int
+
+	// Highest IDs in use, so generated IDs can follow them
+	maxRelID         int
+	maxNumID         int
+	maxAbstractNumID int
+}
+
+var (
+	documentStartPattern = regexp.MustCompile(`<w:document\b[^>]*>`)
+	relIDPattern         = regexp.MustCompile(`Id="rId(\d+)"`)
+	numIDPattern         = regexp.MustCompile(`<w:num\b[^>]*w:numId="(\d+)"`)
+	abstractNumIDPattern = regexp.MustCompile(`<w:abstractNum\b[^>]*w:abstractNumId="(\d+)"`)
+	pageWidthPattern     = regexp.MustCompile(`<w:pgSz\b[^>]*w:w="(\d+)"`)
+	marginLeftPattern    = regexp.MustCompile(`<w:pgMar\b[^>]*w:left="(\d+)"`)
+	marginRightPattern   = regexp.MustCompile(`<w:pgMar\b[^>]*w:right="(\d+)"`)
+	styleBlockPattern    = regexp.MustCompile(`(?s)<w:style\b[^>]*>.*?</w:style>`)
+	styleIDPattern       = regexp.MustCompile(`^<w:style\b[^>]*w:styleId="([^"]+)"`)
+	styleNamePattern     = regexp.MustCompile(`<w:name w:val="([^"]+)"`)
+	styleRefPattern      = regexp.MustCompile(`(<w:(?:pStyle|rStyle|tblStyle|basedOn|next|link) w:val=")([^"]+)(")`)
+)
+
+// Namespaces required by generated body content
+var documentNamespaces = []struct{ prefix, uri string }{
+	{"w", "http://schemas.openxmlformats.org/wordprocessingml/2006/main"},
+	{"r", "http://schemas.openxmlformats.org/officeDocument/2006/relationships"},
+	{"wp", "http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"},
+	{"a", "http://schemas.openxmlformats.org/drawingml/2006/main"},
+	{"pic", "http://schemas.openxmlformats.org/drawingml/2006/picture"},
+	{"m", "http://schemas.openxmlformats.org/officeDocument/2006/math"},
+}
+
+// loadReferenceDocx reads a template document into memory
+func loadReferenceDocx(path string) (*referenceDocx, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	ref := &referenceDocx{parts: map[string][]byte{}}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		ref.parts[f.Name] = data
+		ref.names = append(ref.names, f.Name)
+	}
+
+	document, ok := ref.parts["word/document.xml"]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a Word document: word/document.xml not found", path)
+	}
+	doc := string(document)
+
+	ref.documentStart = documentStartPattern.FindString(doc)
+	if ref.documentStart == "" {
+		return nil, fmt.Errorf("%s has no <w:document> element", path)
+	}
+	ref.documentStart = withNamespaces(ref.documentStart)
+
+	// The body-level section properties are the last ones in the body
+	if end := strings.LastIndex(doc, "</w:sectPr>"); end >= 0 {
+		if start := strings.LastIndex(doc[:end], "<w:sectPr"); start >= 0 {
+			ref.sectPr = doc[start : end+len("</w:sectPr>")]
+		}
+	}
+	width := lastInt(pageWidthPattern, ref.sectPr)
+	left := lastInt(marginLeftPattern, ref.sectPr)
+	right := lastInt(marginRightPattern, ref.sectPr)
+	if width > left+right {
+		ref.contentWidth = width - left - right
+	}
+
+	ref.maxRelID = maxInt(relIDPattern, string(ref.parts["word/_rels/document.xml.rels"]))
+	ref.maxNumID = maxInt(numIDPattern, string(ref.parts["word/numbering.xml"]))
+	ref.maxAbstractNumID = maxInt(abstractNumIDPattern, string(ref.parts["word/numbering.xml"]))
+
+	return ref, nil
+}
+
+// has reports whether the template contains the given part
+func (r *referenceDocx) has(name string) bool {
+	_, ok := r.parts[name]
+	return ok
+}
+
+// writeReferenceParts writes the template's parts with the generated body,
+// merging styles, numbering, relationships and content types
+func (c *Converter) writeReferenceParts(w *zip.Writer) error {
+	ref := c.reference
+
+	addStyles := !ref.has("word/styles.xml")
+	addNumbering := len(c.numbering) > 0 && !ref.has("word/numbering.xml")
+	addCoreProps := !ref.has(corePropsPart)
+	addAppProps := !ref.has(appPropsPart)
+	notes := c.noteKind()
+	addNotes := len(c.notes) > 0 && !ref.has(notes.part())
+	addNoteRels := len(c.noteRels) > 0 && !ref.has(notes.relsPart())
+
+	if addStyles {
+		c.addRelationship(relTypeStyles, "styles.xml", false)
+	}
+	if addNumbering {
+		c.addRelationship(relTypeNumbering, "numbering.xml", false)
+	}
+	if addNotes {
+		c.addRelationship(notes.RelType, notes.target(), false)
+	}
+
+	styles, styleMap := c.mergeStyles()
+
+	// The sectPr carries the template's header and footer references
+	sectPr := ref.sectPr
+	if sectPr == "" {
+		sectPr = c.sectPrXML()
+	}
+	document := mapStyleRefs(c.documentXML(ref.documentStart, sectPr), styleMap)
+
+	for _, name := range ref.names {
+		data := string(ref.parts[name])
+
+		switch name {
+		case "word/document.xml":
+			data = document
+		case "word/styles.xml":
+			data = styles
+		case "word/numbering.xml":
+			if len(c.numbering) > 0 {
+				data = c.mergeNumbering(data)
+			}
+		case notes.part():
+			if len(c.notes) > 0 {
+				data = mapStyleRefs(c.notesXML(data), styleMap)
+			}
+		case notes.relsPart():
+			if len(c.notes) > 0 {
+				data = c.noteRelsXML()
+			}
+		case "word/_rels/document.xml.rels":
+			data = insertBefore(data, "</Relationships>", relationshipsXML(c.relationships)+"\n")
+		case "_rels/.rels":
+			data = addPackageRels(data, addCoreProps, addAppProps)
+		case "[Content_Types].xml":
+			data = c.mergeContentTypes(data, addStyles, addNumbering)
+			if addNotes {
+				data = insertBefore(data, "</Types>", `<Override PartName="/`+notes.part()+`" ContentType="`+notes.ContentType+`"/>`)
+			}
+			if addCoreProps {
+				data = insertBefore(data, "</Types>", `<Override PartName="/`+corePropsPart+`" ContentType="`+contentTypeCoreProps+`"/>`)
+			}
+			if addAppProps {
+				data = insertBefore(data, "</Types>", `<Override PartName="/`+appPropsPart+`" ContentType="`+contentTypeAppProps+`"/>`)
+			}
+		case corePropsPart:
+			// The template's properties describe the template, not this
+			// document
+			data = c.corePropsXML()
+		case appPropsPart:
+			data = c.appPropsXML()
+		}
+
+		if err := addFileToZip(w, name, data); err != nil {
+			return err
+		}
+	}
+
+	if err := c.writeMedia(w); err != nil {
+		return err
+	}
+	if addNumbering {
+		if err := addFileToZip(w, "word/numbering.xml", c.numberingXML()); err != nil {
+			return err
+		}
+	}
+	if addStyles {
+		if err := addFileToZip(w, "word/styles.xml", styles); err != nil {
+			return err
+		}
+	}
+	if addNotes {
+		if err := addFileToZip(w, notes.part(), mapStyleRefs(c.notesXML(""), styleMap)); err != nil {
+			return err
+		}
+	}
+	if addNoteRels {
+		if err := addFileToZip(w, notes.relsPart(), c.noteRelsXML()); err != nil {
+			return err
+		}
+	}
+	if addCoreProps {
+		if err := addFileToZip(w, corePropsPart, c.corePropsXML()); err != nil {
+			return err
+		}
+	}
+	if addAppProps {
+		if err := addFileToZip(w, appPropsPart, c.appPropsXML()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addPackageRels adds relationships for document property parts missing
+// from the template to its _rels/.rels
+func addPackageRels(rels string, addCoreProps, addAppProps bool) string {
+	next := maxInt(relIDPattern, rels) + 1
+	if addCoreProps {
+		rels = insertBefore(rels, "</Relationships>", fmt.Sprintf(`<Relationship Id="rId%d" Type="%s" Target="%s"/>`, next, relTypeCoreProps, corePropsPart))
+		next++
+	}
+	if addAppProps {
+		rels = insertBefore(rels, "</Relationships>", fmt.Sprintf(`<Relationship Id="rId%d" Type="%s" Target="%s"/>`, next, relTypeAppProps, appPropsPart))
+	}
+	return rels
+}
+
+// mergeStyles maps the converter's style IDs onto the template's styles,
+// matching by style ID or display name, and appends any styles the template
+// lacks. It returns the resulting styles.xml and the style ID mapping.
+func (c *Converter) mergeStyles() (string, map[string]string) {
+	own := c.stylesXML()
+	template, ok := c.reference.parts["word/styles.xml"]
+	if !ok {
+		return own, map[string]string{}
+	}
+
+	// Index the template's styles by ID and lowercased name
+	byID := map[string]bool{}
+	byName := map[string]string{}
+	for _, block := range styleBlockPattern.FindAllString(string(template), -1) {
+		m := styleIDPattern.FindStringSubmatch(block)
+		if m == nil {
+			continue
+		}
+		byID[m[1]] = true
+		if name := styleNamePattern.FindStringSubmatch(block); name != nil {
+			byName[strings.ToLower(name[1])] = m[1]
+		}
+	}
+
+	styleMap := map[string]string{}
+	var missing []string
+	for _, block := range styleBlockPattern.FindAllString(own, -1) {
+		m := styleIDPattern.FindStringSubmatch(block)
+		if m == nil {
+			continue
+		}
+		id := m[1]
+		if byID[id] {
+			continue
+		}
+		if name := styleNamePattern.FindStringSubmatch(block); name != nil {
+			if templateID, ok := byName[strings.ToLower(name[1])]; ok {
+				styleMap[id] = templateID
+				continue
+			}
+		}
+		missing = append(missing, block)
+	}
+
+	var added strings.Builder
+	for _, block := range missing {
+		// Default styles of the template win over ours
+		block = strings.Replace(block, ` w:default="1"`, "", 1)
+		added.WriteString("  " + mapStyleRefs(block, styleMap) + "\n")
+	}
+
+	return insertBefore(string(template), "</w:styles>", added.String()), styleMap
+}
+
+// mergeNumbering adds the converter's list definitions to the template's
+// numbering.xml. Abstract definitions must precede numbering instances.
+func (c *Converter) mergeNumbering(numbering string) string {
+	if i := strings.Index(numbering, "<w:num "); i >= 0 {
+		numbering = numbering[:i] + c.abstractNumsXML() + "\n" + numbering[i:]
+	} else {
+		numbering = insertBefore(numbering, "</w:numbering>", c.abstractNumsXML()+"\n")
+	}
+	return insertBefore(numbering, "</w:numbering>", c.numsXML()+"\n")
+}
+
+// mergeContentTypes registers media extensions and newly added parts in the
+// template's [Content_Types].xml
+func (c *Converter) mergeContentTypes(contentTypes string, addStyles, addNumbering bool) string {
+	var added strings.Builder
+	lower := strings.ToLower(contentTypes)
+	for _, m := range c.media {
+		entry := fmt.Sprintf(`extension="%s"`, m.Ext)
+		if strings.Contains(lower, entry) {
+			continue
+		}
+		lower += entry
+		added.WriteString(fmt.Sprintf(`<Default Extension="%s" ContentType="%s"/>`, m.Ext, m.ContentType))
+	}
+	if addStyles {
+		added.WriteString(`<Override PartName="/word/styles.xml" ContentType="` + contentTypeStyles + `"/>`)
+	}
+	if addNumbering {
+		added.WriteString(`<Override PartName="/word/numbering.xml" ContentType="` + contentTypeNumbering + `"/>`)
+	}
+	return insertBefore(contentTypes, "</Types>", added.String())
+}
+
+// mapStyleRefs rewrites style references according to styleMap
+func mapStyleRefs(xml string, styleMap map[string]string) string {
+	if len(styleMap) == 0 {
+		return xml
+	}
+	return styleRefPattern.ReplaceAllStringFunc(xml, func(ref string) string {
+		m := styleRefPattern.FindStringSubmatch(ref)
+		if mapped, ok := styleMap[m[2]]; ok {
+			return m[1] + mapped + m[3]
+		}
+		return ref
+	})
+}
+
+// withNamespaces adds any namespace declarations the generated body needs
+// to a <w:document> or notes part start tag
+func withNamespaces(startTag string) string {
+	for _, ns := range documentNamespaces {
+		if !strings.Contains(startTag, "xmlns:"+ns.prefix+"=") {
+			startTag = strings.TrimSuffix(startTag, ">") + fmt.Sprintf(` xmlns:%s="%s">`, ns.prefix, ns.uri)
+		}
+	}
+	return startTag
+}
+
+// insertBefore inserts text before the last occurrence of marker
+func insertBefore(s, marker, text string) string {
+	i := strings.LastIndex(s, marker)
+	if i < 0 {
+		return s
+	}
+	return s[:i] + text + s[i:]
+}
+
+// maxInt returns the largest integer captured by pattern in s
+func maxInt(pattern *regexp.Regexp, s string) int {
+	max := 0
+	for _, m := range pattern.FindAllStringSubmatch(s, -1) {
+		if n, err := strconv.Atoi(m[1]); err == nil && n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+// lastInt returns the last integer captured by pattern in s
+func lastInt(pattern *regexp.Regexp, s string) int {
+	matches := pattern.FindAllStringSubmatch(s, -1)
+	if len(matches) == 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(matches[len(matches)-1][1])
+	return n
+}
@@ -0,0 +1,156 @@
+package converter
+
+import (
+	"archive/zip"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// writePackage writes a package with the given parts
+func writePackage(t *testing.T, path string, parts map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, data := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// duplicates returns the values captured by pattern more than once in s
+func duplicates(pattern string, s string) []string {
+	seen := map[string]int{}
+	var dups []string
+	for _, m := range regexp.MustCompile(pattern).FindAllStringSubmatch(s, -1) {
+		if seen[m[1]]++; seen[m[1]] == 2 {
+			dups = append(dups, m[1])
+		}
+	}
+	return dups
+}
+
+func TestReferenceDocx(t *testing.T) {
+	const (
+		ns    = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+		rels  = `xmlns="http://schemas.openxmlformats.org/package/2006/relationships"`
+		types = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	)
+	reference := filepath.Join(t.TempDir(), "reference.docx")
+	writePackage(t, reference, map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Default Extension="png" ContentType="image/png"/><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/><Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/><Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/><Override PartName="/word/header1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/></Types>`,
+		"_rels/.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships ` + rels + `><Relationship Id="rId1" Type="` + types + `officeDocument" Target="word/document.xml"/></Relationships>`,
+		"word/_rels/document.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships ` + rels + `><Relationship Id="rId1" Type="` + types + `header" Target="header1.xml"/><Relationship Id="rId2" Type="` + types + `styles" Target="styles.xml"/><Relationship Id="rId3" Type="` + types + `numbering" Target="numbering.xml"/><Relationship Id="rId4" Type="` + types + `image" Target="media/image1.png"/></Relationships>`,
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document ` + ns + ` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><w:body><w:p><w:r><w:t>Template text</w:t></w:r></w:p><w:sectPr><w:headerReference w:type="default" r:id="rId1"/><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1440" w:right="1000" w:bottom="1440" w:left="1000" w:header="708" w:footer="708" w:gutter="0"/></w:sectPr></w:body></w:document>`,
+		"word/styles.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles ` + ns + `><w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:rPr><w:rFonts w:ascii="Corporate Sans"/></w:rPr></w:style><w:style w:type="paragraph" w:styleId="Kop1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:pPr><w:outlineLvl w:val="0"/></w:pPr></w:style><w:style w:type="paragraph" w:styleId="BodyText"><w:name w:val="Body Text"/><w:basedOn w:val="Normal"/></w:style></w:styles>`,
+		"word/numbering.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:numbering ` + ns + `><w:abstractNum w:abstractNumId="0"><w:lvl w:ilvl="0"><w:numFmt w:val="upperRoman"/></w:lvl></w:abstractNum><w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num></w:numbering>`,
+		"word/header1.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:hdr ` + ns + `><w:p><w:r><w:t>Corporate</w:t></w:r></w:p></w:hdr>`,
+		"word/media/image1.png": string(pngImage(t, 1, 1)),
+	})
+
+	image := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngImage(t, 4, 4))
+	markdown := "# Title\n\nText and ![Logo](" + image + ")\n\n```\ncode\n```\n\n1. One\n2. Two\n"
+	parts := convertParts(t, markdown, Options{ReferenceDocx: reference})
+	doc := parts["word/document.xml"]
+
+	// The template's header, section and page size stay; its body goes
+	if parts["word/header1.xml"] == "" || !strings.Contains(doc, `<w:headerReference w:type="default" r:id="rId1"/>`) {
+		t.Error("template header lost")
+	}
+	if strings.Contains(doc, "Template text") || !strings.Contains(doc, ">Title</w:t>") {
+		t.Error("body not replaced")
+	}
+	if parts["word/media/image1.png"] != string(pngImage(t, 1, 1)) {
+		t.Error("template image lost")
+	}
+
+	// Styles: the template's heading style is used under its own ID, and
+	// styles the template lacks are added once, without a second default
+	styles := parts["word/styles.xml"]
+	defs := styleDefinitions(t, styles)
+	for _, id := range styleUses(doc) {
+		if _, ok := defs[id]; !ok {
+			t.Errorf("style %s used but not defined", id)
+		}
+	}
+	if !strings.Contains(doc, `<w:pStyle w:val="Kop1"/>`) || strings.Contains(doc, `"Heading1"`) {
+		t.Error("heading not mapped to the template's heading 1 style")
+	}
+	if _, ok := defs["Heading1"]; ok {
+		t.Error("Heading1 added although the template has heading 1")
+	}
+	if _, ok := defs["SourceCode"]; !ok {
+		t.Error("SourceCode style missing")
+	}
+	if !strings.Contains(defs["Normal"], "Corporate Sans") {
+		t.Error("template's Normal style replaced")
+	}
+	if n := strings.Count(styles, `w:type="paragraph" w:default="1"`); n != 1 {
+		t.Errorf("%d default paragraph styles", n)
+	}
+
+	// Relationships: generated IDs follow the template's
+	targets := relationshipTargets(t, parts["word/_rels/document.xml.rels"])
+	if targets["rId1"] != "header1.xml" || targets["rId4"] != "media/image1.png" {
+		t.Errorf("template relationships changed: %v", targets)
+	}
+	for _, m := range regexp.MustCompile(`r:(?:id|embed)="([^"]+)"`).FindAllStringSubmatch(doc, -1) {
+		if _, ok := targets[m[1]]; !ok {
+			t.Errorf("document refers to missing relationship %s", m[1])
+		}
+	}
+	embed := regexp.MustCompile(`<a:blip r:embed="([^"]+)"/>`).FindStringSubmatch(doc)
+	if embed == nil || targets[embed[1]] != "media/image2.png" {
+		t.Errorf("image embedded as %v, want media/image2.png", embed)
+	}
+
+	// Numbering: generated lists follow the template's definitions
+	numbering := parts["word/numbering.xml"]
+	if dups := duplicates(`<w:num w:numId="(\d+)"`, numbering); len(dups) != 0 {
+		t.Errorf("numbering instances %v defined twice", dups)
+	}
+	if dups := duplicates(`<w:abstractNum w:abstractNumId="(\d+)"`, numbering); len(dups) != 0 {
+		t.Errorf("abstract numbering %v defined twice", dups)
+	}
+	for _, m := range regexp.MustCompile(`<w:numId w:val="(\d+)"/>`).FindAllStringSubmatch(doc, -1) {
+		if m[1] == "1" || !strings.Contains(numbering, `<w:num w:numId="`+m[1]+`"`) {
+			t.Errorf("list uses numbering instance %s", m[1])
+		}
+	}
+
+	// Content types: every extension and part is declared once
+	contentTypes := parts["[Content_Types].xml"]
+	if dups := duplicates(`<Default Extension="([^"]+)"`, strings.ToLower(contentTypes)); len(dups) != 0 {
+		t.Errorf("extensions %v declared twice", dups)
+	}
+	if dups := duplicates(`<Override PartName="([^"]+)"`, contentTypes); len(dups) != 0 {
+		t.Errorf("parts %v declared twice", dups)
+	}
+	for name := range parts {
+		if strings.HasSuffix(name, ".xml") && !strings.HasPrefix(name, "[") && !strings.Contains(contentTypes, `PartName="/`+name+`"`) {
+			t.Errorf("part %s has no content type", name)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Font sizes in half-points for headings
+var headingSizes = map[int]int{
+	1: 48, // 24pt
+	2: 40, // 20pt
+	3: 32, // 16pt
+	4: 28, // 14pt
+	5: 24, // 12pt
+	6: 22, // 11pt
+}
+
+// fontsXML creates an <w:rFonts> element using latin for Latin text and the
+// configured East Asian and complex script fonts for the other script slots
+func (c *Converter) fontsXML(latin string) string {
+	var attrs strings.Builder
+	if latin != "" {
+		attrs.WriteString(fmt.Sprintf(` w:ascii="%s" w:hAnsi="%s"`, escapeXML(latin), escapeXML(latin)))
+	}
+	if c.opts.EastAsianFont != "" {
+		attrs.WriteString(fmt.Sprintf(` w:eastAsia="%s"`, escapeXML(c.opts.EastAsianFont)))
+	}
+	if c.opts.ComplexScriptFont != "" {
+		attrs.WriteString(fmt.Sprintf(` w:cs="%s"`, escapeXML(c.opts.ComplexScriptFont)))
+	}
+	if attrs.Len() == 0 {
+		return ""
+	}
+	return "<w:rFonts" + attrs.String() + "/>"
+}
+
+// stylesXML creates word/styles.xml. Paragraphs and runs reference these
+// named styles so the document can be restyled globally in Word, and the
+// heading outline levels drive the navigation pane and tables of contents.
+func (c *Converter) stylesXML() string {
+	fontSize := int(c.opts.FontSize * 2) // Convert to half-points
+	codeFontSize := int(c.opts.CodeFontSize * 2)
+	noteFontSize := fontSize * 5 / 6 // 10pt notes for 12pt text
+	bodyFonts := c.fontsXML(c.opts.FontFamily)
+	codeFonts := c.fontsXML(c.opts.CodeFontFamily)
+
+	var styles strings.Builder
+	styles.WriteString(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:docDefaults>
+    <w:rPrDefault>
+      <w:rPr>
+        %s
+        <w:sz w:val="%d"/>
+        <w:szCs w:val="%d"/>
+      </w:rPr>
+    </w:rPrDefault>
+    <w:pPrDefault>
+      <w:pPr>
+        <w:spacing w:after="0" w:line="259" w:lineRule="auto"/>
+      </w:pPr>
+    </w:pPrDefault>
+  </w:docDefaults>
+  <w:style w:type="paragraph" w:default="1" w:styleId="Normal">
+    <w:name w:val="Normal"/>
+    <w:qFormat/>
+  </w:style>
+  <w:style w:type="character" w:default="1" w:styleId="DefaultParagraphFont">
+    <w:name w:val="Default Paragraph Font"/>
+    <w:uiPriority w:val="1"/>
+    <w:semiHidden/>
+  </w:style>
+  <w:style w:type="table" w:default="1" w:styleId="TableNormal">
+    <w:name w:val="Normal Table"/>
+    <w:semiHidden/>
+    <w:tblPr>
+      <w:tblInd w:w="0" w:type="dxa"/>
+      <w:tblCellMar>
+        <w:top w:w="0" w:type="dxa"/>
+        <w:left w:w="108" w:type="dxa"/>
+        <w:bottom w:w="0" w:type="dxa"/>
+        <w:right w:w="108" w:type="dxa"/>
+      </w:tblCellMar>
+    </w:tblPr>
+  </w:style>
+  <w:style w:type="paragraph" w:styleId="Title">
+    <w:name w:val="Title"/>
+    <w:basedOn w:val="Normal"/>
+    <w:next w:val="BodyText"/>
+    <w:qFormat/>
+    <w:pPr>
+      <w:keepNext/>
+      <w:spacing w:before="480" w:after="240"/>
+      <w:jc w:val="center"/>
+    </w:pPr>
+    <w:rPr>
+      <w:b/>
+      <w:sz w:val="56"/>
+      <w:szCs w:val="56"/>
+    </w:rPr>
+  </w:style>
+  <w:style w:type="paragraph" w:styleId="Subtitle">
+    <w:name w:val="Subtitle"/>
+    <w:basedOn w:val="Title"/>
+    <w:next w:val="BodyText"/>
+    <w:qFormat/>
+    <w:pPr>
+      <w:spacing w:before="0" w:after="240"/>
+    </w:pPr>
+    <w:rPr>
+      <w:b w:val="0"/>
+      <w:color w:val="6A737D"/>
+      <w:sz w:val="32"/>
+      <w:szCs w:val="32"/>
+    </w:rPr>
+  </w:style>
+  <w:style w:type="paragraph" w:styleId="Author">
+    <w:name w:val="Author"/>
+    <w:basedOn w:val="Normal"/>
+    <w:next w:val="BodyText"/>
+    <w:qFormat/>
+    <w:pPr>
+      <w:keepNext/>
+      <w:spacing w:after="80"/>
+      <w:jc w:val="center"/>
+    </w:pPr>
+  </w:style>
+  <w:style w:type="paragraph" w:styleId="Date">
+    <w:name w:val="Date"/>
+    <w:basedOn w:val="Normal"/>
+    <w:next w:val="BodyText"/>
+    <w:qFormat/>
+    <w:pPr>
+      <w:keepNext/>
+      <w:spacing w:before="240"/>
+      <w:jc w:val="center"/>
+    </w:pPr>
+  </w:style>`, bodyFonts, fontSize, fontSize))
+
+	for level := 1; level <= 6; level++ {
+		size := headingSizes[level]
+		styles.WriteString(fmt.Sprintf(`
+  <w:style w:type="paragraph" w:styleId="Heading%d">
+    <w:name w:val="heading %d"/>
+    <w:basedOn w:val="Normal"/>
+    <w:next w:val="BodyText"/>
+    <w:uiPriority w:val="9"/>
+    <w:qFormat/>
+    <w:pPr>
+      <w:keepNext/>
+      <w:keepLines/>
+      <w:spacing w:before="240" w:after="120"/>
+      <w:outlineLvl w:val="%d"/>
+    </w:pPr>
+    <w:rPr>
+      <w:b/>
+      <w:bCs/>
+      <w:sz w:val="%d"/>
+      <w:szCs w:val="%d"/>
+    </w:rPr>
+  </w:style>`, level, level, level-1, size, size))
+	}
+
+	styles.WriteString(fmt.Sprintf(`
+  <w:style w:type="paragraph" w:styleId="BodyText">
+    <w:name w:val="Body Text"/>
+    <w:basedOn w:val="Normal"/>
+    <w:qFormat/>
+    <w:pPr>
+      <w:spacing w:after="160"/>
+    </w:pPr>
+  </w:style>
+  <w:style w:type="paragraph" w:styleId="SourceCode">
+    <w:name w:val="Source Code"/>
+    <w:basedOn w:val="Normal"/>
+    <w:qFormat/>
+    <w:pPr>
+      <w:shd w:val="clear" w:color="auto" w:fill="F6F8FA"/>
+      <w:ind w:left="360"/>
+      <w:spacing w:after="0" w:line="240" w:lineRule="auto"/>
+    </w:pPr>
+    <w:rPr>
+      %s
+      <w:sz w:val="%d"/>
+      <w:szCs w:val="%d"/>
+    </w:rPr>
+  </w:style>
+  <w:style w:type="paragraph" w:styleId="BlockQuote">
+    <w:name w:val="Block Text"/>
+    <w:basedOn w:val="BodyText"/>
+    <w:qFormat/>
+    <w:pPr>
+      <w:pBdr>
+        <w:left w:val="single" w:sz="24" w:space="4" w:color="DFE2E5"/>
+      </w:pBdr>
+      <w:ind w:left="720"/>
+    </w:pPr>
+    <w:rPr>
+      <w:i/>
+      <w:iCs/>
+      <w:color w:val="6A737D"/>
+    </w:rPr>
+  </w:style>
+  <w:style w:type="paragraph" w:styleId="ListParagraph">
+    <w:name w:val="List Paragraph"/>
+    <w:basedOn w:val="Normal"/>
+    <w:uiPriority w:val="34"/>
+    <w:qFormat/>
+    <w:pPr>
+      <w:spacing w:after="80"/>
+      <w:ind w:left="720"/>
+    </w:pPr>
+  </w:style>
+  <w:style w:type="character" w:styleId="Hyperlink">
+    <w:name w:val="Hyperlink"/>
+    <w:basedOn w:val="DefaultParagraphFont"/>
+    <w:uiPriority w:val="99"/>
+    <w:unhideWhenUsed/>
+    <w:rPr>
+      <w:color w:val="0563C1"/>
+      <w:u w:val="single"/>
+    </w:rPr>
+  </w:style>
+  <w:style w:type="character" w:styleId="VerbatimChar">
+    <w:name w:val="Verbatim Char"/>
+    <w:basedOn w:val="DefaultParagraphFont"/>
+    <w:rPr>
+      %s
+      <w:sz w:val="%d"/>
+      <w:szCs w:val="%d"/>
+      <w:shd w:val="clear" w:color="auto" w:fill="EFF1F3"/>
+    </w:rPr>
+  </w:style>
+  <w:style w:type="paragraph" w:styleId="FootnoteText">
+    <w:name w:val="footnote text"/>
+    <w:basedOn w:val="Normal"/>
+    <w:uiPriority w:val="99"/>
+    <w:unhideWhenUsed/>
+    <w:pPr>
+      <w:spacing w:after="40"/>
+    </w:pPr>
+    <w:rPr>
+      <w:sz w:val="%d"/>
+      <w:szCs w:val="%d"/>
+    </w:rPr>
+  </w:style>
+  <w:style w:type="character" w:styleId="FootnoteReference">
+    <w:name w:val="footnote reference"/>
+    <w:basedOn w:val="DefaultParagraphFont"/>
+    <w:uiPriority w:val="99"/>
+    <w:unhideWhenUsed/>
+    <w:rPr>
+      <w:vertAlign w:val="superscript"/>
+    </w:rPr>
+  </w:style>
+  <w:style w:type="paragraph" w:styleId="EndnoteText">
+    <w:name w:val="endnote text"/>
+    <w:basedOn w:val="Normal"/>
+    <w:uiPriority w:val="99"/>
+    <w:unhideWhenUsed/>
+    <w:pPr>
+      <w:spacing w:after="40"/>
+    </w:pPr>
+    <w:rPr>
+      <w:sz w:val="%d"/>
+      <w:szCs w:val="%d"/>
+    </w:rPr>
+  </w:style>
+  <w:style w:type="character" w:styleId="EndnoteReference">
+    <w:name w:val="endnote reference"/>
+    <w:basedOn w:val="DefaultParagraphFont"/>
+    <w:uiPriority w:val="99"/>
+    <w:unhideWhenUsed/>
+    <w:rPr>
+      <w:vertAlign w:val="superscript"/>
+    </w:rPr>
+  </w:style>
+  <w:style w:type="table" w:styleId="TableGrid">
+    <w:name w:val="Table Grid"/>
+    <w:basedOn w:val="TableNormal"/>
+    <w:uiPriority w:val="39"/>
+    <w:tblPr>
+      <w:tblBorders>
+        <w:top w:val="single" w:sz="4" w:space="0" w:color="DFE2E5"/>
+        <w:left w:val="single" w:sz="4" w:space="0" w:color="DFE2E5"/>
+        <w:bottom w:val="single" w:sz="4" w:space="0" w:color="DFE2E5"/>
+        <w:right w:val="single" w:sz="4" w:space="0" w:color="DFE2E5"/>
+        <w:insideH w:val="single" w:sz="4" w:space="0" w:color="DFE2E5"/>
+        <w:insideV w:val="single" w:sz="4" w:space="0" w:color="DFE2E5"/>
+      </w:tblBorders>
+    </w:tblPr>
+  </w:style>
+</w:styles>`, codeFonts, codeFontSize, codeFontSize, codeFonts, codeFontSize, codeFontSize, noteFontSize, noteFontSize, noteFontSize, noteFontSize))
+
+	return styles.String()
+}
@@ -0,0 +1,121 @@
+package converter
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// styleDefinitions returns the style definitions of a styles part by ID,
+// failing on IDs defined twice
+func styleDefinitions(t *testing.T, styles string) map[string]string {
+	t.Helper()
+	defs := map[string]string{}
+	for _, block := range styleBlockPattern.FindAllString(styles, -1) {
+		m := styleIDPattern.FindStringSubmatch(block)
+		if m == nil {
+			t.Errorf("style without an ID: %s", block)
+			continue
+		}
+		if _, ok := defs[m[1]]; ok {
+			t.Errorf("style %s defined twice", m[1])
+		}
+		defs[m[1]] = block
+	}
+	return defs
+}
+
+// styleUses returns the sorted IDs of the styles a part refers to
+func styleUses(part string) []string {
+	used := map[string]bool{}
+	for _, m := range regexp.MustCompile(`<w:(?:pStyle|rStyle|tblStyle) w:val="([^"]+)"/>`).FindAllStringSubmatch(part, -1) {
+		used[m[1]] = true
+	}
+	var ids []string
+	for id := range used {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestNamedStyles(t *testing.T) {
+	markdown := "# One\n\n## Two\n\n### Three\n\n#### Four\n\n##### Five\n\n###### Six\n\n" +
+		"Text with `code` and a [link](https://example.com).\n\n" +
+		"```\ncode block\n```\n\n" +
+		"> Quoted\n\n" +
+		"- Item\n\n" +
+		"| A |\n|---|\n| B |\n"
+
+	parts := convertParts(t, markdown, Options{})
+	defs := styleDefinitions(t, parts["word/styles.xml"])
+	used := styleUses(parts["word/document.xml"])
+	for _, id := range used {
+		if _, ok := defs[id]; !ok {
+			t.Errorf("style %s used but not defined", id)
+		}
+	}
+
+	want := []string{"BlockQuote", "BodyText", "Heading1", "Heading2", "Heading3", "Heading4", "Heading5", "Heading6",
+		"Hyperlink", "ListParagraph", "SourceCode", "TableGrid", "VerbatimChar"}
+	if strings.Join(used, " ") != strings.Join(want, " ") {
+		t.Errorf("got styles %q, want %q", used, want)
+	}
+
+	// Headings drive the navigation pane through their outline level, and
+	// take their look from the style rather than direct formatting
+	for level := 1; level <= 6; level++ {
+		id := "Heading" + strconv.Itoa(level)
+		if !strings.Contains(defs[id], `<w:outlineLvl w:val="`+strconv.Itoa(level-1)+`"/>`) {
+			t.Errorf("%s has no outline level %d", id, level-1)
+		}
+	}
+	heading := regexp.MustCompile(`(?s)<w:pStyle w:val="Heading1"/>.*?</w:p>`).FindString(parts["word/document.xml"])
+	if heading == "" || strings.Contains(heading, "<w:sz ") || strings.Contains(heading, "<w:b/>") {
+		t.Errorf("heading formatted directly: %s", heading)
+	}
+}
+
+func TestFonts(t *testing.T) {
+	markdown := "Text with `code`\n\n```go\nfunc main() {}\n```\n"
+	opts := Options{FontFamily: "Body & Co", CodeFontFamily: "Fira Code", EastAsianFont: "SimSun", ComplexScriptFont: "Arial"}
+
+	parts := convertParts(t, markdown, opts)
+	styles := parts["word/styles.xml"]
+	defs := styleDefinitions(t, styles)
+	defaults := regexp.MustCompile(`(?s)<w:docDefaults>.*?</w:docDefaults>`).FindString(styles)
+
+	body := `<w:rFonts w:ascii="Body &amp; Co" w:hAnsi="Body &amp; Co" w:eastAsia="SimSun" w:cs="Arial"/>`
+	if !strings.Contains(defaults, body) {
+		t.Errorf("default fonts: %s", defaults)
+	}
+	code := `<w:rFonts w:ascii="Fira Code" w:hAnsi="Fira Code" w:eastAsia="SimSun" w:cs="Arial"/>`
+	for _, id := range []string{"SourceCode", "VerbatimChar"} {
+		if !strings.Contains(defs[id], code) {
+			t.Errorf("%s fonts: %s", id, defs[id])
+		}
+	}
+
+	// Runs take their fonts from the styles, so changing a style changes
+	// them all
+	doc := parts["word/document.xml"]
+	if strings.Contains(doc, "<w:rFonts") {
+		t.Error("fonts set directly on runs")
+	}
+	if !strings.Contains(doc, `<w:rStyle w:val="VerbatimChar"/>`) || !strings.Contains(doc, `<w:pStyle w:val="SourceCode"/>`) {
+		t.Error("code styles not used")
+	}
+
+	// Unset fonts are left to Word's defaults
+	parts = convertParts(t, markdown, Options{})
+	if strings.Contains(parts["word/styles.xml"], "<w:rFonts") {
+		t.Error("fonts set without options")
+	}
+	parts = convertParts(t, markdown, Options{CodeFontFamily: "Consolas"})
+	defs = styleDefinitions(t, parts["word/styles.xml"])
+	if !strings.Contains(defs["SourceCode"], `<w:rFonts w:ascii="Consolas" w:hAnsi="Consolas"/>`) {
+		t.Errorf("SourceCode fonts: %s", defs["SourceCode"])
+	}
+}
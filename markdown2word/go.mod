@@ -3,11 +3,17 @@ module github.com/example/markdown2word
 go 1.22.4
 
 require (
+	github.com/alecthomas/chroma/v2 v2.2.0
+	github.com/example/shared v0.0.0
 	github.com/spf13/cobra v1.10.2
 	github.com/yuin/goldmark v1.7.13
 )
 
 require (
+	github.com/dlclark/regexp2 v1.7.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/example/shared => ../shared
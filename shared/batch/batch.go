@@ -0,0 +1,328 @@
+// Package batch converts many Markdown files in one command for the
+// markdown2pdf and markdown2word tools. It expands directories and glob
+// patterns into jobs, skips jobs whose output is up to date, converts the
+// rest in parallel and prints a summary.
+package batch
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job is one Markdown file to convert
+type Job struct {
+	Input  string
+	Output string
+}
+
+// result is the outcome of a job
+type result struct {
+	Status   string // "converted", "skipped" or "failed"
+	Err      error
+	Duration time.Duration
+}
+
+// Collect expands command line arguments into jobs. Arguments may be files,
+// directories (searched recursively if recursive is set) or glob patterns,
+// including "**" for any number of directories. Outputs get the extension
+// outExt and go next to their inputs, or mirror the input tree under outDir.
+// Two inputs that would write the same output are an error.
+func Collect(args []string, outExt, outDir string, recursive bool) ([]Job, error) {
+	var jobs []Job
+	seen := map[string]bool{}
+	inputs := map[string]string{} // output to the input that writes it
+
+	add := func(input, base string) error {
+		abs, err := filepath.Abs(input)
+		if err == nil && seen[abs] {
+			return nil
+		}
+		seen[abs] = true
+
+		output := strings.TrimSuffix(input, filepath.Ext(input)) + outExt
+		if outDir != "" {
+			rel, err := filepath.Rel(base, input)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				rel = filepath.Base(input)
+			}
+			output = filepath.Join(outDir, strings.TrimSuffix(rel, filepath.Ext(rel))+outExt)
+		}
+		key := output
+		if abs, err := filepath.Abs(output); err == nil {
+			key = abs
+		}
+		if other, ok := inputs[key]; ok {
+			return fmt.Errorf("%s and %s would both be converted to %s", other, input, output)
+		}
+		inputs[key] = input
+		jobs = append(jobs, Job{Input: input, Output: output})
+		return nil
+	}
+
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		switch {
+		case err == nil && info.IsDir():
+			files, err := markdownFiles(arg, recursive)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range files {
+				if err := add(f, arg); err != nil {
+					return nil, err
+				}
+			}
+		case err == nil:
+			if err := add(arg, filepath.Dir(arg)); err != nil {
+				return nil, err
+			}
+		case IsGlob(arg):
+			base, matches, err := expandGlob(arg)
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", arg)
+			}
+			for _, f := range matches {
+				if err := add(f, base); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("input file does not exist: %s", arg)
+		}
+	}
+
+	return jobs, nil
+}
+
+// IsMarkdown reports whether a path has a Markdown extension
+func IsMarkdown(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// markdownFiles lists the Markdown files in dir, descending into
+// subdirectories if recurse is set
+func markdownFiles(dir string, recurse bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (!recurse || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if IsMarkdown(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// IsGlob reports whether a path contains glob metacharacters
+func IsGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// expandGlob returns the directory before the first wildcard and the files
+// matching pattern. Unlike filepath.Glob, "**" matches any number of
+// directories, so patterns work the same whether or not the shell expands
+// them.
+func expandGlob(pattern string) (string, []string, error) {
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+	i := 0
+	for i < len(parts) && !IsGlob(parts[i]) {
+		i++
+	}
+	base := filepath.FromSlash(strings.Join(parts[:i], "/"))
+	if base == "" {
+		base = "."
+		if strings.HasPrefix(pattern, "/") {
+			base = "/"
+		}
+	}
+	rest := parts[i:]
+
+	var matches []string
+	err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Like shells, wildcards don't match hidden files and directories
+		if path != base && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return nil
+		}
+		if matchSegments(rest, strings.Split(filepath.ToSlash(rel), "/")) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return base, matches, err
+}
+
+// matchSegments matches path segments against pattern segments, where "**"
+// matches zero or more segments
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		for skip := 0; skip <= len(path); skip++ {
+			if matchSegments(pattern[1:], path[skip:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// upToDate reports whether a job's output is newer than its input and the
+// files every conversion depends on. Only modification times are compared,
+// so changes to other options aren't noticed.
+func upToDate(j Job, deps []string) bool {
+	out, err := os.Stat(j.Output)
+	if err != nil {
+		return false
+	}
+	for _, path := range append([]string{j.Input}, deps...) {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().After(out.ModTime()) {
+			return false
+		}
+	}
+	return true
+}
+
+// ConvertFunc converts the file of a job, returning warnings to print
+type ConvertFunc func(j Job) (warnings []string, err error)
+
+// Run converts the jobs whose output isn't up to date, unless force is set,
+// with a pool of workers, then prints a summary. Once there is anything to
+// convert, start is called with the number of workers; it returns the
+// function converting a job and one releasing what the conversions shared.
+// Run returns an error if any file failed, after trying all of them.
+func Run(jobs []Job, workers int, force bool, deps []string, start func(workers int) (ConvertFunc, func(), error)) error {
+	results := make([]result, len(jobs))
+
+	var pending []int
+	for i, j := range jobs {
+		if !force && upToDate(j, deps) {
+			results[i] = result{Status: "skipped"}
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	if len(pending) > 0 {
+		if workers < 1 {
+			workers = 1
+		}
+		if workers > len(pending) {
+			workers = len(pending)
+		}
+
+		convert, done, err := start(workers)
+		if err != nil {
+			return err
+		}
+		defer done()
+
+		var mu sync.Mutex // Serializes progress output
+		queue := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range queue {
+					results[i] = runJob(jobs[i], convert, &mu)
+				}
+			}()
+		}
+		for _, i := range pending {
+			queue <- i
+		}
+		close(queue)
+		wg.Wait()
+	}
+
+	return printSummary(jobs, results)
+}
+
+// runJob converts a single file, creating its output directory first
+func runJob(j Job, convert ConvertFunc, mu *sync.Mutex) result {
+	mu.Lock()
+	fmt.Printf("Converting %s to %s...\n", j.Input, j.Output)
+	mu.Unlock()
+
+	start := time.Now()
+	if err := os.MkdirAll(filepath.Dir(j.Output), 0755); err != nil {
+		return result{Status: "failed", Err: fmt.Errorf("failed to create output directory: %w", err)}
+	}
+
+	warnings, err := convert(j)
+
+	mu.Lock()
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", j.Input, warning)
+	}
+	mu.Unlock()
+
+	if err != nil {
+		return result{Status: "failed", Err: err, Duration: time.Since(start)}
+	}
+	return result{Status: "converted", Duration: time.Since(start)}
+}
+
+// printSummary prints one line per file and the totals, returning an error
+// if any file failed
+func printSummary(jobs []Job, results []result) error {
+	counts := map[string]int{}
+	fmt.Println("\nSummary:")
+	for i, r := range results {
+		counts[r.Status]++
+		switch r.Status {
+		case "converted":
+			fmt.Printf("  converted  %s -> %s (%.1fs)\n", jobs[i].Input, jobs[i].Output, r.Duration.Seconds())
+		case "skipped":
+			fmt.Printf("  skipped    %s (up to date)\n", jobs[i].Input)
+		case "failed":
+			fmt.Printf("  failed     %s: %v\n", jobs[i].Input, r.Err)
+		}
+	}
+	fmt.Printf("%d files: %d converted, %d skipped, %d failed\n",
+		len(jobs), counts["converted"], counts["skipped"], counts["failed"])
+
+	if counts["failed"] > 0 {
+		return fmt.Errorf("%d of %d files failed to convert", counts["failed"], len(jobs))
+	}
+	return nil
+}
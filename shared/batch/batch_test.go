@@ -0,0 +1,223 @@
+package batch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeFiles creates empty files under dir
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// outputs returns the relative output paths of jobs, sorted
+func outputs(t *testing.T, dir string, jobs []Job) []string {
+	t.Helper()
+	var paths []string
+	for _, j := range jobs {
+		rel, err := filepath.Rel(dir, j.Output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.md", "b.markdown", "notes.txt", "sub/c.md", "sub/deep/d.md", ".hidden/e.md")
+	out := filepath.Join(dir, "out")
+
+	tests := []struct {
+		name      string
+		args      []string
+		outDir    string
+		recursive bool
+		want      []string
+	}{
+		{"directory", []string{dir}, "", false, []string{"a.pdf", "b.pdf"}},
+		{"recursive", []string{dir}, "", true, []string{"a.pdf", "b.pdf", "sub/c.pdf", "sub/deep/d.pdf"}},
+		{"out dir mirrors tree", []string{dir}, out, true, []string{"out/a.pdf", "out/b.pdf", "out/sub/c.pdf", "out/sub/deep/d.pdf"}},
+		{"glob", []string{filepath.Join(dir, "*.md")}, "", false, []string{"a.pdf"}},
+		{"double star", []string{filepath.Join(dir, "**", "*.md")}, out, false, []string{"out/a.pdf", "out/sub/c.pdf", "out/sub/deep/d.pdf"}},
+		{"file named twice", []string{filepath.Join(dir, "a.md"), filepath.Join(dir, "*.md")}, "", false, []string{"a.pdf"}},
+		{"other file", []string{filepath.Join(dir, "notes.txt")}, "", false, []string{"notes.pdf"}},
+	}
+	for _, tt := range tests {
+		jobs, err := Collect(tt.args, ".pdf", tt.outDir, tt.recursive)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := outputs(t, dir, jobs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := Collect([]string{filepath.Join(dir, "missing.md")}, ".pdf", "", false); err == nil {
+		t.Error("missing file: no error")
+	}
+	if _, err := Collect([]string{filepath.Join(dir, "*.rst")}, ".pdf", "", false); err == nil {
+		t.Error("glob without matches: no error")
+	}
+}
+
+func TestCollectOutputCollision(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.md", "a.markdown", "one/doc.md", "two/doc.md")
+	out := filepath.Join(dir, "out")
+
+	tests := []struct {
+		name   string
+		args   []string
+		outDir string
+		inputs []string
+	}{
+		{"same name in one directory", []string{filepath.Join(dir, "a.md"), filepath.Join(dir, "a.markdown")}, "", []string{"a.md", "a.markdown"}},
+		{"out dir", []string{filepath.Join(dir, "one", "doc.md"), filepath.Join(dir, "two", "doc.md")}, out, []string{"one/doc.md", "two/doc.md"}},
+	}
+	for _, tt := range tests {
+		_, err := Collect(tt.args, ".pdf", tt.outDir, false)
+		if err == nil {
+			t.Errorf("%s: no error", tt.name)
+			continue
+		}
+		for _, input := range tt.inputs {
+			if !strings.Contains(err.Error(), filepath.Join(dir, filepath.FromSlash(input))) {
+				t.Errorf("%s: %q does not name %s", tt.name, err, input)
+			}
+		}
+	}
+
+	// Outputs next to their inputs keep files of the same name apart
+	if _, err := Collect([]string{filepath.Join(dir, "one"), filepath.Join(dir, "two")}, ".pdf", "", false); err != nil {
+		t.Errorf("outputs next to inputs: %v", err)
+	}
+}
+
+func TestUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "doc.md", "doc.pdf", "style.css")
+	j := Job{Input: filepath.Join(dir, "doc.md"), Output: filepath.Join(dir, "doc.pdf")}
+	deps := []string{filepath.Join(dir, "style.css")}
+
+	now := time.Now()
+	touch := func(name string, age time.Duration) {
+		if err := os.Chtimes(filepath.Join(dir, name), now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	touch("doc.md", 2*time.Hour)
+	touch("style.css", 2*time.Hour)
+	touch("doc.pdf", time.Hour)
+	if !upToDate(j, deps) {
+		t.Error("output newer than everything is not up to date")
+	}
+
+	touch("style.css", time.Minute)
+	if upToDate(j, deps) {
+		t.Error("output older than a dependency is up to date")
+	}
+	if !upToDate(j, nil) {
+		t.Error("output newer than its input is not up to date")
+	}
+
+	touch("doc.md", time.Minute)
+	if upToDate(j, nil) {
+		t.Error("output older than its input is up to date")
+	}
+
+	if upToDate(Job{Input: j.Input, Output: filepath.Join(dir, "none.pdf")}, nil) {
+		t.Error("missing output is up to date")
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "fresh.md", "fresh.out", "stale.md", "bad.md")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "fresh.md"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	jobs := []Job{
+		{Input: filepath.Join(dir, "fresh.md"), Output: filepath.Join(dir, "fresh.out")},
+		{Input: filepath.Join(dir, "stale.md"), Output: filepath.Join(dir, "new", "stale.out")},
+		{Input: filepath.Join(dir, "bad.md"), Output: filepath.Join(dir, "bad.out")},
+	}
+
+	for _, force := range []bool{false, true} {
+		var mu sync.Mutex
+		var converted []string
+		started, done := 0, false
+		start := func(workers int) (ConvertFunc, func(), error) {
+			started++
+			convert := func(j Job) ([]string, error) {
+				mu.Lock()
+				converted = append(converted, filepath.Base(j.Input))
+				mu.Unlock()
+				if filepath.Base(j.Input) == "bad.md" {
+					return nil, errors.New("broken")
+				}
+				return nil, nil
+			}
+			return convert, func() { done = true }, nil
+		}
+
+		err := Run(jobs, 4, force, nil, start)
+		if err == nil {
+			t.Errorf("force=%v: failed file not reported", force)
+		}
+		if started != 1 || !done {
+			t.Errorf("force=%v: started %d times, done %v", force, started, done)
+		}
+		sort.Strings(converted)
+		want := []string{"bad.md", "stale.md"}
+		if force {
+			want = []string{"bad.md", "fresh.md", "stale.md"}
+		}
+		if !reflect.DeepEqual(converted, want) {
+			t.Errorf("force=%v: converted %v, want %v", force, converted, want)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "new")); err != nil {
+			t.Errorf("force=%v: output directory not created: %v", force, err)
+		}
+	}
+}
+
+func TestRunWithNothingToDo(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "doc.md")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "doc.md"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, "doc.out")
+
+	err := Run([]Job{{Input: filepath.Join(dir, "doc.md"), Output: filepath.Join(dir, "doc.out")}}, 2, false, nil,
+		func(int) (ConvertFunc, func(), error) {
+			t.Error("started without anything to convert")
+			return nil, nil, errors.New("unexpected")
+		})
+	if err != nil {
+		t.Error(err)
+	}
+}
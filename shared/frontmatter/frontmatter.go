@@ -0,0 +1,102 @@
+// Package frontmatter reads the YAML block at the top of a Markdown
+// document, delimited by "---" lines, and the document metadata both
+// converters take from it. Each converter decodes its own option overrides
+// from the same block.
+package frontmatter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Metadata describes the document. Converters embed it inline in their
+// front matter types.
+type Metadata struct {
+	Title    string     `yaml:"title"`
+	Author   StringList `yaml:"author"`
+	Subject  string     `yaml:"subject"`
+	Keywords StringList `yaml:"keywords"`
+	Date     string     `yaml:"date"`
+	Lang     string     `yaml:"lang"`
+}
+
+// StringList accepts either a single string or a list of strings
+type StringList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = StringList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Parse splits a leading front matter block off markdown and decodes it
+// into v, reporting whether there was one. The block is replaced by blank
+// lines so that line numbers in the body still match the source file. A
+// document without front matter is returned as is.
+func Parse(markdown []byte, v interface{}) (bool, []byte, error) {
+	content := bytes.TrimPrefix(markdown, []byte("\ufeff"))
+	lines := strings.SplitAfter(string(content), "\n")
+	if len(lines) == 0 || strings.TrimRight(lines[0], "\r\n") != "---" {
+		return false, markdown, nil
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t\r\n")
+		if line == "---" || line == "..." {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return false, markdown, nil
+	}
+
+	// A block that isn't a YAML mapping, such as text between two thematic
+	// breaks, is Markdown. Only a mapping with bad fields is an error.
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[1:end], "")), &doc); err != nil {
+		return false, markdown, nil
+	}
+	if len(doc.Content) > 0 {
+		if doc.Content[0].Kind != yaml.MappingNode {
+			return false, markdown, nil
+		}
+		if err := doc.Content[0].Decode(v); err != nil {
+			return false, nil, fmt.Errorf("invalid front matter: %w", err)
+		}
+	}
+
+	body := strings.Repeat("\n", end+1) + strings.Join(lines[end+1:], "")
+	return true, []byte(body), nil
+}
+
+// Date layouts accepted for the front matter date
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseDate parses the front matter date, reporting whether it could
+func ParseDate(value string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
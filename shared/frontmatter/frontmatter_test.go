@@ -0,0 +1,117 @@
+package frontmatter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// document is front matter as converters declare it: metadata and option
+// overrides
+type document struct {
+	Metadata `yaml:",inline"`
+	Margin   *float64 `yaml:"margin"`
+}
+
+func TestParse(t *testing.T) {
+	margin := 2.5
+	tests := []struct {
+		name     string
+		markdown string
+		want     *document
+		body     string
+	}{
+		{
+			name:     "mapping",
+			markdown: "---\ntitle: Report\nauthor: [Ada, Grace]\nkeywords: go\nmargin: 2.5\n---\nBody\n",
+			want: &document{
+				Metadata: Metadata{Title: "Report", Author: StringList{"Ada", "Grace"}, Keywords: StringList{"go"}},
+				Margin:   &margin,
+			},
+			body: "\n\n\n\n\n\nBody\n",
+		},
+		{
+			name:     "byte order mark and dots",
+			markdown: "\ufeff---\r\ntitle: Report\r\n...\r\nBody\r\n",
+			want:     &document{Metadata: Metadata{Title: "Report"}},
+			body:     "\n\n\nBody\r\n",
+		},
+		{
+			name:     "empty block",
+			markdown: "---\n---\nBody\n",
+			want:     &document{},
+			body:     "\n\nBody\n",
+		},
+		{
+			name:     "thematic breaks around text",
+			markdown: "---\nSome text\n---\nMore text\n",
+		},
+		{
+			name:     "thematic breaks around a list",
+			markdown: "---\n- one\n- two\n---\n",
+		},
+		{
+			name:     "thematic breaks around text that isn't YAML",
+			markdown: "---\n* emphasis* and `code: here`\n---\n",
+		},
+		{
+			name:     "no closing delimiter",
+			markdown: "---\ntitle: Report\n",
+		},
+		{
+			name:     "not at the start",
+			markdown: "Text\n---\ntitle: Report\n---\n",
+		},
+	}
+
+	for _, tt := range tests {
+		got := &document{}
+		found, body, err := Parse([]byte(tt.markdown), got)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if found != (tt.want != nil) {
+			t.Errorf("%s: found %v", tt.name, found)
+		}
+		want := tt.body
+		if tt.want == nil {
+			tt.want, want = &document{}, tt.markdown
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got front matter %+v, want %+v", tt.name, got, tt.want)
+		}
+		if string(body) != want {
+			t.Errorf("%s: got body %q, want %q", tt.name, body, want)
+		}
+	}
+}
+
+func TestParseRejectsBadFields(t *testing.T) {
+	_, _, err := Parse([]byte("---\nmargin: wide\n---\nBody\n"), &document{})
+	if err == nil || !strings.Contains(err.Error(), "invalid front matter") {
+		t.Errorf("got %v, want an invalid front matter error", err)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{" 2024-03-05 14:30:00 ", time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC), true},
+		{"2024-03-05T14:30:00", time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC), true},
+		{"2024-03-05T14:30:00+02:00", time.Date(2024, 3, 5, 12, 30, 0, 0, time.UTC), true},
+		{"March 2024", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseDate(tt.value)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/example/shared
+
+go 1.22.4
+
+require (
+	github.com/yuin/goldmark v1.7.13
+	gopkg.in/yaml.v3 v3.0.1
+)
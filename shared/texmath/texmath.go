@@ -0,0 +1,211 @@
+// Package texmath parses TeX math delimited by $ and $$ in Markdown into
+// goldmark nodes holding the TeX source. The converters render the nodes:
+// markdown2pdf with KaTeX and markdown2word as Word equations.
+package texmath
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindInline is the node kind of math within a paragraph
+var KindInline = ast.NewNodeKind("Math")
+
+// KindBlock is the node kind of display math on lines of its own
+var KindBlock = ast.NewNodeKind("MathBlock")
+
+// Inline is $...$ or $$...$$ math within a paragraph
+type Inline struct {
+	ast.BaseInline
+	TeX     []byte
+	Display bool
+}
+
+// Kind implements ast.Node
+func (n *Inline) Kind() ast.NodeKind {
+	return KindInline
+}
+
+// Dump implements ast.Node
+func (n *Inline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"TeX": string(n.TeX)}, nil)
+}
+
+// Block is display math starting with a $$ line and ending with a line
+// ending in $$. Its lines hold the TeX source.
+type Block struct {
+	ast.BaseBlock
+	closed bool
+}
+
+// Kind implements ast.Node
+func (n *Block) Kind() ast.NodeKind {
+	return KindBlock
+}
+
+// IsRaw implements ast.Node
+func (n *Block) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node
+func (n *Block) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// Extension recognizes TeX math delimited by $ (inline) and $$ (display).
+// It adds parsers only; renderers are up to the converters.
+var Extension goldmark.Extender = &extension{}
+
+type extension struct{}
+
+// Extend implements goldmark.Extender
+func (e *extension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&blockParser{}, 150)),
+		parser.WithInlineParsers(util.Prioritized(&inlineParser{}, 150)),
+	)
+}
+
+// inlineParser parses math within a line. Like Pandoc, the opening $
+// must be followed by a non-space character and the closing $ preceded by
+// one and not followed by a digit, so that prices such as $5 and $10 stay
+// text. Math ends a line at the latest and can't contain code spans.
+type inlineParser struct{}
+
+// Trigger implements parser.InlineParser
+func (p *inlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Parse implements parser.InlineParser
+func (p *inlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	delim := 1
+	if len(line) > 1 && line[1] == '$' {
+		delim = 2
+	}
+	if len(line) <= delim || util.IsSpace(line[delim]) {
+		return nil
+	}
+
+	for i := delim; i < len(line); i++ {
+		switch {
+		case line[i] == '\\':
+			i++ // Skip the escaped character, e.g. \$
+		case line[i] == '`':
+			return nil // Code spans take precedence
+		case line[i] != '$':
+		case delim == 2:
+			if i+1 < len(line) && line[i+1] == '$' {
+				block.Advance(i + 2)
+				return &Inline{TeX: line[2:i], Display: true}
+			}
+			return nil
+		case util.IsSpace(line[i-1]) || (i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9'):
+		default:
+			block.Advance(i + 1)
+			return &Inline{TeX: line[1:i]}
+		}
+	}
+	return nil
+}
+
+// blockParser parses display math on lines of its own:
+//
+//	$$
+//	\int_0^1 x^2 \, dx
+//	$$
+//
+// The source may also start on the opening line and end on the closing one.
+type blockParser struct{}
+
+// Trigger implements parser.BlockParser
+func (p *blockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Open implements parser.BlockParser
+func (p *blockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+
+	node := &Block{}
+	start := pos + 2
+	rest := util.TrimRightSpace(line[start:])
+	if end := bytes.Index(rest, []byte("$$")); end >= 0 {
+		// Math within a paragraph, such as "$$x$$ is ...", is left to the
+		// inline parser
+		if end != len(rest)-2 {
+			return nil, parser.NoChildren
+		}
+		rest = rest[:end]
+		node.closed = true
+	}
+	if !util.IsBlank(rest) {
+		end := segment.Start + start + len(rest)
+		if !node.closed {
+			// Keep the line break, which separates the line from the next
+			end = segment.Stop
+		}
+		node.Lines().Append(text.NewSegment(segment.Start+start, end))
+	}
+	advanceLine(reader, line, segment)
+	return node, parser.NoChildren
+}
+
+// Continue implements parser.BlockParser
+func (p *blockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	block := node.(*Block)
+	if block.closed {
+		return parser.Close
+	}
+
+	line, segment := reader.PeekLine()
+	trimmed := util.TrimRightSpace(line)
+	if bytes.HasSuffix(trimmed, []byte("$$")) {
+		if content := trimmed[:len(trimmed)-2]; !util.IsBlank(content) {
+			block.Lines().Append(text.NewSegment(segment.Start, segment.Start+len(content)))
+		}
+		advanceLine(reader, line, segment)
+		block.closed = true
+		return parser.Close
+	}
+
+	seg := segment
+	seg.ForceNewline = true
+	block.Lines().Append(seg)
+	advanceLine(reader, line, segment)
+	return parser.Continue | parser.NoChildren
+}
+
+// advanceLine moves the reader to the end of the current line, leaving the
+// line break to the parser
+func advanceLine(reader text.Reader, line []byte, segment text.Segment) {
+	n := segment.Len()
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		n--
+	}
+	reader.Advance(n)
+}
+
+// Close implements parser.BlockParser
+func (p *blockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+// CanInterruptParagraph implements parser.BlockParser
+func (p *blockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+// CanAcceptIndentedLine implements parser.BlockParser
+func (p *blockParser) CanAcceptIndentedLine() bool {
+	return false
+}
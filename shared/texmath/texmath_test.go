@@ -0,0 +1,86 @@
+package texmath
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// parseMath parses markdown and describes its math and code spans in
+// document order
+func parseMath(markdown string) []string {
+	source := []byte(markdown)
+	doc := goldmark.New(goldmark.WithExtensions(Extension)).Parser().Parse(text.NewReader(source))
+
+	var found []string
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *Inline:
+			kind := "inline"
+			if n.Display {
+				kind = "display"
+			}
+			found = append(found, kind+" "+string(n.TeX))
+		case *Block:
+			var tex strings.Builder
+			for i := 0; i < n.Lines().Len(); i++ {
+				segment := n.Lines().At(i)
+				tex.Write(segment.Value(source))
+			}
+			found = append(found, "block "+tex.String())
+		case *ast.CodeSpan:
+			found = append(found, "code "+string(n.Text(source)))
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return found
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+	}{
+		{"inline", "Area $\\pi r^2$ here", []string{`inline \pi r^2`}},
+		{"two inline", "$a$ and $b$", []string{"inline a", "inline b"}},
+		{"display within a line", "So $$x = 1$$ holds", []string{"display x = 1"}},
+		{"escaped opening dollar", `Costs \$5 and $x$`, []string{"inline x"}},
+		{"escaped dollar inside", `$a \$ b$`, []string{`inline a \$ b`}},
+		{"escaped closing dollar", `$a\$ b`, nil},
+		{"space after opening", "$ x$", nil},
+		{"space before closing", "$x $", nil},
+		{"currency", "Between $5 and $10 a day", nil},
+		{"digit after closing", "$x$5", nil},
+		{"digit inside", "$x_1$", []string{"inline x_1"}},
+		{"lone dollar", "Just $ a sign", nil},
+		{"unterminated inline", "Ends $x", nil},
+		{"math ends with the line", "$a\nb$", nil},
+		{"unterminated inline display", "So $$x = 1 holds", nil},
+		{"math inside a code span", "`$x$` stays code", []string{"code $x$"}},
+		{"code span inside math", "$a `b` c$", []string{"code b"}},
+		{"math after a code span", "`$` then $x$", []string{"code $", "inline x"}},
+		{"block", "$$\nx^2\n$$\n", []string{"block x^2\n"}},
+		{"block of several lines", "$$\na \\\\\nb\n$$\n", []string{"block a \\\\\nb\n"}},
+		{"block on one line", "$$x^2$$\n", []string{"block x^2"}},
+		{"block sharing delimiter lines", "$$ \\alpha\nb $$\n", []string{"block  \\alpha\nb "}},
+		{"block interrupting a paragraph", "Text\n$$\nx\n$$\nmore", []string{"block x\n"}},
+		{"unterminated block", "$$\nx\n\nText\n", []string{"block x\n\nText\n"}},
+		{"block in a code block", "```\n$$\nx\n$$\n```\n", nil},
+		{"indented block", "    $$\n    x\n    $$\n", nil},
+	}
+
+	for _, tt := range tests {
+		if got := parseMath(tt.markdown); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %q\n got %q\nwant %q", tt.name, tt.markdown, got, tt.want)
+		}
+	}
+}